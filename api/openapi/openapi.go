@@ -0,0 +1,7 @@
+// Package openapi embeds the OpenAPI specification of the REST API, which is served and enforced at runtime.
+package openapi
+
+import _ "embed"
+
+//go:embed person-service.yaml
+var Spec []byte
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/outbox"
 	"github.com/Inspirate789/ds-lab1/internal/person/repository"
 	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/person/webhook"
 	"github.com/Inspirate789/ds-lab1/internal/pkg/app"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -16,42 +19,58 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
 
-type WebApp interface {
+type App interface {
 	Start() error
 	Shutdown(ctx context.Context) error
 }
 
-func startApp(webApp WebApp, config app.Config, logger *slog.Logger) {
-	logger.Debug(fmt.Sprintf("web app starts at %s with configuration: %+v", config.Web.Host+":"+config.Web.Port, config))
+func startApp(name string, a App, address string, logger *slog.Logger) {
+	logger.Debug(fmt.Sprintf("%s starts at %s", name, address))
 
 	go func() {
-		err := webApp.Start()
+		err := a.Start()
 		if err != nil {
 			panic(err)
 		}
 	}()
 }
 
-func shutdownApp(webApp WebApp, logger *slog.Logger) {
+// shutdownApps waits for the termination signal and stops the apps. All of them share the shutdown timeout.
+func shutdownApps(logger *slog.Logger, apps ...App) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	logger.Debug("shutdown web app ...")
+	logger.Debug("shutdown apps ...")
 
 	const shutdownTimeout = time.Minute
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 
-	err := webApp.Shutdown(ctx)
+	var wg sync.WaitGroup
+	errs := make([]error, len(apps))
+
+	for i, a := range apps {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			errs[i] = a.Shutdown(ctx)
+		}()
+	}
+
+	wg.Wait()
+	cancel()
+
+	err := errors.Join(errs...)
 	if err != nil {
 		panic(err)
 	}
 
-	cancel()
-	logger.Debug("web app exited")
+	logger.Debug("apps exited")
 }
 
 func main() {
@@ -89,9 +108,43 @@ func main() {
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.Level(config.Logging.Level)}))
 	repo := repository.NewSqlxRepository(db, logger)
-	useCase := usecase.New(repo, logger)
-	webApp := app.NewFiberApp(config.Web, useCase, logger)
+	webhookService := webhook.New(repository.NewSqlxWebhookRepository(db, logger), config.Webhooks, logger)
+	events, err := delivery.NewEventFeed(context.Background(), config.Web.Events, repository.NewSqlxOutboxReader(db, logger), logger)
+	if err != nil {
+		panic(err)
+	}
+
+	useCase := usecase.New(repo, config.Persons, logger)
+	webApp := app.NewFiberApp(config.Web, useCase, logger, app.WithWebhooks(webhookService), app.WithEventFeed(events))
+	grpcApp := app.NewGRPCApp(config.GRPC, useCase, logger)
 
-	startApp(webApp, config, logger)
-	shutdownApp(webApp, logger)
+	publisher, err := outbox.OpenPublisher(config.Outbox.Publisher)
+	if err != nil {
+		panic(err)
+	}
+
+	publishers := outbox.MultiPublisher{webhookService, publisher}
+	relay := outbox.New(repository.NewSqlxOutboxRepository(db, logger), publishers, config.Outbox, logger)
+	relayDone := make(chan struct{})
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go useCase.RunPurge(jobsCtx)
+	go webhookService.Run(jobsCtx)
+	go events.Run(jobsCtx)
+	go func() {
+		defer close(relayDone)
+		relay.Run(jobsCtx)
+	}()
+
+	logger.Debug(fmt.Sprintf("configuration: %+v", config))
+	startApp("web app", webApp, config.Web.Host+":"+config.Web.Port, logger)
+	startApp("gRPC app", grpcApp, config.GRPC.Host+":"+config.GRPC.Port, logger)
+	shutdownApps(logger, webApp, grpcApp)
+	stopJobs()
+	<-relayDone // the publisher is closed after the last batch is published
+
+	err = publisher.Close()
+	if err != nil {
+		panic(err)
+	}
 }
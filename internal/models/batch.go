@@ -0,0 +1,48 @@
+package models
+
+import "strconv"
+
+type BatchOperationType string
+
+const (
+	BatchCreate BatchOperationType = "create"
+	BatchPatch  BatchOperationType = "patch"
+	BatchDelete BatchOperationType = "delete"
+)
+
+// BatchOperation is one operation of the batch applied in one transaction.
+type BatchOperation struct {
+	Type BatchOperationType
+	// PersonID is the target of patch and delete operations. It is ignored if Ref is set.
+	PersonID int
+	// Ref is the index of the earlier create operation of the batch whose person is the target.
+	Ref     *int
+	Version int64
+	// Person is the new person of the create operation.
+	Person PersonProperties
+	// Patch is the change of the patch operation.
+	Patch PersonPatch
+}
+
+// Target returns the ID of the person changed by the operation with the persons of the preceding operations.
+func (o BatchOperation) Target(results []Person) int {
+	if o.Ref != nil {
+		return results[*o.Ref].ID
+	}
+
+	return o.PersonID
+}
+
+// BatchOperationError is the error of the batch operation. Nothing of the batch is applied if it is returned.
+type BatchOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e BatchOperationError) Error() string {
+	return "batch operation " + strconv.Itoa(e.Index) + ": " + e.Err.Error()
+}
+
+func (e BatchOperationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,22 @@
+package models
+
+import "errors"
+
+var (
+	ErrVersionMismatch       = errors.New("person version mismatch")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was used for another request")
+	ErrPersonNotFound        = errors.New("person not found")
+	ErrDeletedPersonNotFound = errors.New("deleted person not found")
+	ErrPersonDeleted         = errors.New("person is deleted, restore it before replacing")
+	ErrInvalidBatchRef       = errors.New("reference must point to an earlier create operation of the batch")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrForbiddenWebhookURL   = errors.New("webhook URL must resolve to public addresses")
+	ErrPatchTestFailed       = errors.New("JSON patch test operation failed")
+)
+
+// FieldErrors is the error of the invalid person fields, e.g. set by the patch, keyed by the fields.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	return "invalid person fields"
+}
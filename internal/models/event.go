@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// PersonEventType is the type of the change of the person notified to the subscribers.
+type PersonEventType string
+
+const (
+	PersonCreated PersonEventType = "person.created"
+	PersonUpdated PersonEventType = "person.updated"
+	PersonDeleted PersonEventType = "person.deleted"
+)
+
+func (t PersonEventType) Valid() bool {
+	switch t {
+	case PersonCreated, PersonUpdated, PersonDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// PersonEvent is the stored change of the person with the snapshot of the person after the change.
+type PersonEvent struct {
+	ID         string
+	Type       PersonEventType
+	Person     Person
+	OccurredAt time.Time
+}
+
+// OutboxEvent is the person event stored in the outbox by the transaction of the change.
+// Sequence is the position of the event in the outbox, the events are published in its order.
+type OutboxEvent struct {
+	Sequence int64
+	PersonEvent
+}
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+type HistoryOperation string
+
+const (
+	HistoryCreate  HistoryOperation = "create"
+	HistoryUpdate  HistoryOperation = "update"
+	HistoryDelete  HistoryOperation = "delete"
+	HistoryRestore HistoryOperation = "restore"
+	HistoryPurge   HistoryOperation = "purge"
+)
+
+// PersonHistoryEntry is a snapshot of the person before and after the change.
+// Before is nil for created persons, After is nil for purged ones.
+type PersonHistoryEntry struct {
+	ID        int64
+	PersonID  int
+	Version   int64
+	Operation HistoryOperation
+	Before    *Person
+	After     *Person
+	Actor     string
+	RequestID string
+	ChangedAt time.Time
+}
+
+type FieldChange struct {
+	Field  string
+	Before any
+	After  any
+}
+
+var historyFields = []string{
+	string(PersonFieldName),
+	string(PersonFieldAge),
+	string(PersonFieldAddress),
+	string(PersonFieldWork),
+	"deleted_at",
+}
+
+func historyValues(person *Person) map[string]any {
+	values := make(map[string]any, len(historyFields))
+	if person == nil {
+		return values
+	}
+
+	values[string(PersonFieldName)] = person.Name
+	values[string(PersonFieldAddress)] = person.Address
+	values[string(PersonFieldWork)] = person.Work
+
+	if person.Age != nil {
+		values[string(PersonFieldAge)] = *person.Age
+	}
+
+	if person.DeletedAt != nil {
+		values["deleted_at"] = *person.DeletedAt
+	}
+
+	return values
+}
+
+func equalHistoryValues(a, b any) bool {
+	timeA, okA := a.(time.Time)
+	timeB, okB := b.(time.Time)
+
+	if okA && okB {
+		return timeA.Equal(timeB)
+	}
+
+	return a == b
+}
+
+// Changes returns the field-level diff between the snapshots. Values missing in the snapshot are nil.
+func (e PersonHistoryEntry) Changes() []FieldChange {
+	before, after := historyValues(e.Before), historyValues(e.After)
+	changes := make([]FieldChange, 0, len(historyFields))
+
+	for _, field := range historyFields {
+		if equalHistoryValues(before[field], after[field]) {
+			continue
+		}
+
+		changes = append(changes, FieldChange{Field: field, Before: before[field], After: after[field]})
+	}
+
+	return changes
+}
+
+type PersonHistoryQuery struct {
+	PersonID int
+	Offset   int64
+	Limit    int64
+}
+
+type PersonHistoryPage struct {
+	Entries []PersonHistoryEntry
+	HasMore bool
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey identifies the create request, so that its retries do not create duplicates.
+// The fingerprint of the request body detects reuse of the key for another request.
+type IdempotencyKey struct {
+	Key         string
+	Fingerprint string
+	TTL         time.Duration
+}
+
+type IdempotentResult struct {
+	PersonID int
+	// Replayed reports whether the result was stored by the previous request with the same key.
+	Replayed bool
+}
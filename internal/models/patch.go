@@ -0,0 +1,7 @@
+package models
+
+// PersonPatch changes the person inside the update transaction,
+// so the patch preconditions are checked against the current state of the person.
+type PersonPatch interface {
+	Apply(person Person) (Person, error)
+}
@@ -1,13 +1,45 @@
 package models
 
+import "time"
+
+// AnyVersion disables the optimistic concurrency check of the person version.
+const AnyVersion int64 = 0
+
 type Person struct {
 	ID int
 	PersonProperties
+	// Version is incremented on every update of the person.
+	Version int64
+	// DeletedAt is set for persons moved to the trash.
+	DeletedAt *time.Time
 }
 
 type PersonProperties struct {
-	Name    string
-	Age     int
+	Name string
+	// Age is nil if it is unknown.
+	Age     *int
 	Address string
 	Work    string
 }
+
+// FieldValue returns the value of the field, the unknown age is nil.
+func (p Person) FieldValue(field PersonField) any {
+	switch field {
+	case PersonFieldID:
+		return p.ID
+	case PersonFieldName:
+		return p.Name
+	case PersonFieldAge:
+		if p.Age == nil {
+			return nil
+		}
+
+		return *p.Age
+	case PersonFieldAddress:
+		return p.Address
+	case PersonFieldWork:
+		return p.Work
+	default:
+		return nil
+	}
+}
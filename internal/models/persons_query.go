@@ -0,0 +1,82 @@
+package models
+
+type PersonField string
+
+const (
+	PersonFieldID      PersonField = "id"
+	PersonFieldName    PersonField = "name"
+	PersonFieldAge     PersonField = "age"
+	PersonFieldAddress PersonField = "address"
+	PersonFieldWork    PersonField = "work"
+)
+
+func (f PersonField) Valid() bool {
+	switch f {
+	case PersonFieldID, PersonFieldName, PersonFieldAge, PersonFieldAddress, PersonFieldWork:
+		return true
+	default:
+		return false
+	}
+}
+
+type SortKey struct {
+	Field      PersonField
+	Descending bool
+}
+
+type DeletedFilter string
+
+const (
+	DeletedExclude DeletedFilter = ""
+	DeletedOnly    DeletedFilter = "only"
+	DeletedInclude DeletedFilter = "include"
+)
+
+func (f DeletedFilter) Valid() bool {
+	switch f {
+	case DeletedExclude, DeletedOnly, DeletedInclude:
+		return true
+	default:
+		return false
+	}
+}
+
+type PersonsFilter struct {
+	NameContains    string
+	NamePrefix      string
+	Work            string
+	AgeMin          *int
+	AgeMax          *int
+	AddressContains string
+	Deleted         DeletedFilter
+}
+
+// PersonsQuery selects persons by offset or, if Keyset is set, after (before) the Cursor.
+// A keyset query without a cursor selects the first page.
+// WithTotal requests the total number of persons matching the filter.
+type PersonsQuery struct {
+	Filter    PersonsFilter
+	Sort      []SortKey
+	Offset    int64
+	Limit     int64
+	Keyset    bool
+	Cursor    *Cursor
+	WithTotal bool
+}
+
+// Cursor points to the boundary person of a page for keyset pagination.
+// Forward cursors select persons after the boundary, backward ones select persons before it.
+type Cursor struct {
+	Backward bool
+	Boundary Person
+}
+
+type PersonsPage struct {
+	Persons []Person
+	// HasMore reports whether there are more persons in the scan direction.
+	HasMore    bool
+	NextCursor *Cursor
+	PrevCursor *Cursor
+	// Total is set only for queries with WithTotal flag.
+	Total int64
+}
@@ -0,0 +1,21 @@
+package models
+
+// PersonsSearchQuery is the full-text search of persons by name, address and work.
+// Query has the web search syntax: quoted phrases, "or" and "-" for excluded words.
+type PersonsSearchQuery struct {
+	Query  string
+	Offset int64
+	Limit  int64
+}
+
+type PersonSearchResult struct {
+	Person
+	Rank float64
+	// Highlights are the matched fields with the found words marked.
+	Highlights map[PersonField]string
+}
+
+type PersonsSearchPage struct {
+	Results []PersonSearchResult
+	HasMore bool
+}
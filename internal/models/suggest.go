@@ -0,0 +1,23 @@
+package models
+
+type NameSuggestionMode string
+
+const (
+	// SuggestFuzzy finds names similar to the given one, so typos and transliteration differences are tolerated.
+	SuggestFuzzy NameSuggestionMode = "fuzzy"
+	// SuggestPrefix finds names starting with the given one ignoring case, for type-ahead.
+	SuggestPrefix NameSuggestionMode = "prefix"
+)
+
+type NameSuggestionQuery struct {
+	Name string
+	Mode NameSuggestionMode
+	// Threshold is the minimal trigram similarity of fuzzy suggestions from 0 to 1.
+	Threshold float64
+	Limit     int64
+}
+
+type NameSuggestion struct {
+	Name       string
+	Similarity float64
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+type WebhookProperties struct {
+	URL    string
+	Events []PersonEventType
+	// Secret is the key of the HMAC-SHA256 signatures of the deliveries.
+	Secret string
+}
+
+type Webhook struct {
+	ID int
+	WebhookProperties
+	CreatedAt time.Time
+}
+
+// WebhookEvent is the encoded person event to deliver to the webhooks subscribed to its type.
+type WebhookEvent struct {
+	ID      string
+	Type    PersonEventType
+	Payload []byte
+}
+
+// WebhookDeliveryStatus is the state of the delivery. Pending deliveries are retried until they are delivered
+// or run out of attempts, then they are dead and kept for inspection only.
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliveryDelivered WebhookDeliveryStatus = "delivered"
+	DeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+func (s WebhookDeliveryStatus) Valid() bool {
+	switch s {
+	case DeliveryPending, DeliveryDelivered, DeliveryDead:
+		return true
+	default:
+		return false
+	}
+}
+
+type WebhookDelivery struct {
+	ID            int64
+	WebhookID     int
+	EventID       string
+	EventType     PersonEventType
+	Payload       []byte
+	Status        WebhookDeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	// LastError and LastStatus describe the last failed attempt, LastStatus is 0 if there was no response.
+	LastError   string
+	LastStatus  int
+	CreatedAt   time.Time
+	DeliveredAt *time.Time
+}
+
+// WebhookDeliveryTask is the delivery claimed by the dispatcher with the webhook to send it to.
+type WebhookDeliveryTask struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+type WebhookDeliveriesQuery struct {
+	WebhookID int
+	// Status selects the deliveries in the status, all deliveries are selected if it is empty.
+	Status WebhookDeliveryStatus
+	Offset int64
+	Limit  int64
+}
+
+type WebhookDeliveriesPage struct {
+	Deliveries []WebhookDelivery
+	HasMore    bool
+}
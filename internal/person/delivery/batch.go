@@ -0,0 +1,229 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+)
+
+const (
+	batchCreateVerb      = ":batchCreate"
+	defaultMaxBatchSize  = 1000
+	batchItemErrorsField = "person"
+)
+
+// batchMode defines how invalid persons of the batch are handled. In the atomic mode nothing is created
+// if any person is invalid, in the best effort mode valid persons are created and invalid ones are reported.
+type batchMode string
+
+const (
+	batchAtomic     batchMode = "atomic"
+	batchBestEffort batchMode = "best_effort"
+)
+
+func parseBatchMode(ctx *fiber.Ctx) (batchMode, error) {
+	mode := batchMode(ctx.Query("mode", string(batchAtomic)))
+	if mode != batchAtomic && mode != batchBestEffort {
+		return "", errors.ErrInvalidPersonsQuery(map[string]string{
+			"mode": "must be one of atomic, best_effort",
+		})
+	}
+
+	return mode, nil
+}
+
+// batchItem is the person of the batch. Errors are set if the person is invalid.
+type batchItem struct {
+	Person PersonProperties
+	Errors map[string]string
+}
+
+func (c Config) maxBatchSize() int {
+	if c.MaxBatchSize <= 0 {
+		return defaultMaxBatchSize
+	}
+
+	return c.MaxBatchSize
+}
+
+// splitBatch splits the JSON array of the batch into its elements.
+func splitBatch(body []byte, maxSize int) ([]json.RawMessage, error) {
+	var elements []json.RawMessage
+
+	err := json.Unmarshal(body, &elements)
+	if err != nil {
+		return nil, errors.ErrInvalidBatch("must be a JSON array: " + err.Error())
+	}
+
+	if len(elements) == 0 {
+		return nil, errors.ErrInvalidBatch("must contain at least one element")
+	}
+
+	if len(elements) > maxSize {
+		return nil, errors.ErrInvalidBatch("must contain at most " + strconv.Itoa(maxSize) + " elements")
+	}
+
+	return elements, nil
+}
+
+// parseBatch parses the JSON array of persons. Invalid persons are reported in the result,
+// the error is returned only if the array itself cannot be parsed.
+func parseBatch(body []byte, maxSize int) ([]batchItem, error) {
+	persons, err := splitBatch(body, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]batchItem, 0, len(persons))
+
+	for _, person := range persons {
+		dto, err := parsePersonProperties(person, false)
+
+		var validationErr errors.ValidationError
+		if stderrors.As(err, &validationErr) {
+			fieldErrors := validationErr.Errors
+			if len(fieldErrors) == 0 {
+				fieldErrors = map[string]string{batchItemErrorsField: validationErr.Message}
+			}
+
+			items = append(items, batchItem{Errors: fieldErrors})
+
+			continue
+		}
+
+		items = append(items, batchItem{Person: dto})
+	}
+
+	return items, nil
+}
+
+// createBatch creates the valid persons of the batch with one use case call. The returned flag is false
+// if nothing was created because of invalid persons in the atomic mode. The created persons are aligned
+// with the items, invalid items get the zero person. In the best effort mode the persons rejected by the storage
+// are created one by one, so the errors of the rejected ones are set to their items.
+func (d *delivery) createBatch(ctx context.Context, items []batchItem, mode batchMode) ([]models.Person, bool, error) {
+	valid := make([]models.PersonProperties, 0, len(items))
+
+	for _, item := range items {
+		if item.Errors == nil {
+			valid = append(valid, item.Person.ToProperties())
+		} else if mode == batchAtomic {
+			return nil, false, nil
+		}
+	}
+
+	persons := make([]models.Person, len(items))
+	if len(valid) == 0 {
+		return persons, true, nil
+	}
+
+	created, err := d.useCase.CreatePersons(ctx, valid)
+	if mode == batchBestEffort && isItemError(err) {
+		return d.createBatchItems(ctx, items)
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	for i, item := range items {
+		if item.Errors == nil {
+			persons[i], created = created[0], created[1:]
+		}
+	}
+
+	return persons, true, nil
+}
+
+// isItemError reports whether the error of the use case may be caused by the data of the persons,
+// unlike the errors of the unavailable storage, which fail the whole batch.
+func isItemError(err error) bool {
+	kind := usecase.KindOf(err)
+	return kind == usecase.KindValidation || kind == usecase.KindConflict
+}
+
+// createBatchItems creates the valid persons of the batch one by one and sets the errors of the rejected ones.
+func (d *delivery) createBatchItems(ctx context.Context, items []batchItem) ([]models.Person, bool, error) {
+	persons := make([]models.Person, len(items))
+
+	for i := range items {
+		if items[i].Errors != nil {
+			continue
+		}
+
+		person, err := d.useCase.CreatePerson(ctx, items[i].Person.ToProperties())
+		if isItemError(err) {
+			items[i].Errors = batchItemErrors(err)
+			continue
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		persons[i] = person
+	}
+
+	return persons, true, nil
+}
+
+// batchItemErrors returns the errors of the fields from the problem of the error or its detail.
+func batchItemErrors(err error) map[string]string {
+	details, _ := errors.FromError(err)
+
+	fieldErrors, _ := details.Extensions["errors"].(map[string]string)
+	if len(fieldErrors) == 0 {
+		return map[string]string{batchItemErrorsField: details.Detail}
+	}
+
+	return fieldErrors
+}
+
+// BatchCreatePersons creates persons from the JSON array and reports the result for every person.
+func (d *delivery) BatchCreatePersons(ctx *fiber.Ctx) error {
+	mode, err := parseBatchMode(ctx)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	items, err := parseBatch(ctx.Body(), d.config.maxBatchSize())
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	persons, created, err := d.createBatch(ctx.UserContext(), items, mode)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	report := BatchCreateReport{
+		Mode:    string(mode),
+		Results: make([]BatchCreateResult, len(items)),
+	}
+	personsPath := strings.TrimSuffix(ctx.Path(), batchCreateVerb)
+
+	for i, item := range items {
+		report.Results[i] = BatchCreateResult{Index: i, Errors: item.Errors}
+
+		switch {
+		case item.Errors != nil:
+			report.Failed++
+		case created:
+			report.Results[i].ID = persons[i].ID
+			report.Results[i].Location = personsPath + "/" + strconv.Itoa(persons[i].ID)
+			report.Created++
+		}
+	}
+
+	if !created {
+		return ctx.Status(fiber.StatusBadRequest).JSON(report)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(report)
+}
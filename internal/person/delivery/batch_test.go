@@ -0,0 +1,121 @@
+package delivery_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type BatchCreateSuite struct {
+	suite.Suite
+}
+
+var (
+	firstPerson  = models.PersonProperties{Name: "Aboba", Age: age(20)}
+	secondPerson = models.PersonProperties{Name: "Biba", Age: age(30)}
+)
+
+const batchBody = `[{"name": "Aboba", "age": 20}, {"name": "Biba", "age": 30}, {"name": ""}]`
+
+// age returns the pointer to the age of the person.
+func age(value int) *int {
+	return &value
+}
+
+func (*BatchCreateSuite) send(t provider.T, useCase *UseCaseMock, mode string) (*http.Response, delivery.BatchCreateReport) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New()
+	delivery.AddHandlers(app, delivery.Config{}, useCase, logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/persons:batchCreate?mode="+mode, strings.NewReader(batchBody))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	res, err := app.Test(req, -1)
+	t.Require().NoError(err)
+
+	defer res.Body.Close()
+
+	var report delivery.BatchCreateReport
+	if res.StatusCode == http.StatusOK {
+		t.Require().NoError(json.NewDecoder(res.Body).Decode(&report))
+	}
+
+	return res, report
+}
+
+func (s *BatchCreateSuite) TestBestEffortReportsStorageErrorsPerItem(t provider.T) {
+	t.Epic("Batch")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("CreatePersons", []models.PersonProperties{firstPerson, secondPerson}).
+		Return([]models.Person(nil), usecase.Validation(errors.New("check constraint violated")))
+	useCase.On("CreatePerson", firstPerson).Return(models.Person{ID: 1, PersonProperties: firstPerson}, nil)
+	useCase.On("CreatePerson", secondPerson).
+		Return(models.Person{}, usecase.Validation(models.FieldErrors{"name": "is taken"}))
+	// act
+	res, report := s.send(t, useCase, "best_effort")
+	// assert
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().Equal(1, report.Created)
+	t.Require().Equal(2, report.Failed)
+	t.Require().Equal(1, report.Results[0].ID)
+	t.Require().Nil(report.Results[0].Errors)
+	t.Require().Zero(report.Results[1].ID)
+	t.Require().Equal(map[string]string{"name": "is taken"}, report.Results[1].Errors)
+	t.Require().Contains(report.Results[2].Errors, "name")
+	useCase.AssertExpectations(t)
+}
+
+func (s *BatchCreateSuite) TestBestEffortFailsOnUnavailableStorage(t provider.T) {
+	t.Epic("Batch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("CreatePersons", mock.Anything).
+		Return([]models.Person(nil), usecase.Unavailable(errors.New("connection refused")))
+	// act
+	res, _ := s.send(t, useCase, "best_effort")
+	// assert
+	t.Require().Equal(http.StatusServiceUnavailable, res.StatusCode)
+	useCase.AssertNotCalled(t, "CreatePerson", mock.Anything)
+}
+
+func (s *BatchCreateSuite) TestAtomicRejectsInvalidItems(t provider.T) {
+	t.Epic("Batch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	// act
+	res, _ := s.send(t, useCase, "atomic")
+	// assert
+	t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	useCase.AssertNotCalled(t, "CreatePersons", mock.Anything)
+	useCase.AssertNotCalled(t, "CreatePerson", mock.Anything)
+}
+
+func TestBatchCreate(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(BatchCreateSuite))
+}
@@ -0,0 +1,194 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/csv"
+	stderrors "errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimeTextCSV   = "text/csv; charset=utf-8"
+	maxImportRows = 10000
+	byteOrderMark = "\uFEFF"
+)
+
+// csvColumns are written to the header row of the export. The import accepts the same columns
+// in any order, the id column is ignored.
+var csvColumns = []string{"id", "name", "age", "address", "work"}
+
+// formulaPrefixes start the cells which spreadsheets evaluate as formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeCSVCell prefixes the text starting like a formula with the quote, so that spreadsheets show
+// the text as is instead of evaluating it (CSV injection).
+func escapeCSVCell(value string) string {
+	if value != "" && strings.IndexByte(formulaPrefixes, value[0]) >= 0 {
+		return "'" + value
+	}
+
+	return value
+}
+
+// unescapeCSVCell removes the prefix added by escapeCSVCell, so the exported persons are imported as they were.
+func unescapeCSVCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.IndexByte(formulaPrefixes, value[1]) >= 0 {
+		return value[1:]
+	}
+
+	return value
+}
+
+// personRecord returns the row of the person, the unknown age is an empty cell.
+func personRecord(person models.Person) []string {
+	age := ""
+	if person.Age != nil {
+		age = strconv.Itoa(*person.Age)
+	}
+
+	return []string{
+		strconv.Itoa(person.ID),
+		escapeCSVCell(person.Name),
+		age,
+		escapeCSVCell(person.Address),
+		escapeCSVCell(person.Work),
+	}
+}
+
+// csvPerson is the parsed data row with its line in the CSV.
+type csvPerson struct {
+	batchItem
+	Line int
+}
+
+// parseCSVHeader returns the indexes of the known columns.
+func parseCSVHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	fieldErrors := make(map[string]string)
+
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, byteOrderMark)))
+
+		if !slices.Contains(csvColumns, column) {
+			fieldErrors[column] = "unknown column"
+			continue
+		}
+
+		if _, ok := columns[column]; ok {
+			fieldErrors[column] = "duplicate column"
+			continue
+		}
+
+		columns[column] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		fieldErrors["name"] = "column is required"
+	}
+
+	if len(fieldErrors) != 0 {
+		return nil, errors.ErrInvalidCSV("invalid header row", fieldErrors)
+	}
+
+	return columns, nil
+}
+
+func parseCSVRecord(record []string, columns map[string]int) (PersonProperties, map[string]string) {
+	var dto PersonProperties
+
+	value := func(column string) string {
+		i, ok := columns[column]
+		if !ok {
+			return ""
+		}
+
+		return unescapeCSVCell(strings.TrimSpace(record[i]))
+	}
+
+	dto.Name = value("name")
+	dto.Address = value("address")
+	dto.Work = value("work")
+
+	fieldErrors := make(map[string]string)
+
+	if age := value("age"); age != "" {
+		parsed, err := strconv.Atoi(age)
+		if err != nil {
+			fieldErrors["age"] = errors.FieldWrongType("an integer")
+		} else {
+			dto.Age = &parsed
+		}
+	}
+
+	var validationErr errors.ValidationError
+	if err := dto.Validate(false); stderrors.As(err, &validationErr) {
+		for field, msg := range validationErr.Errors {
+			if _, ok := fieldErrors[field]; !ok {
+				fieldErrors[field] = msg
+			}
+		}
+	}
+
+	if len(fieldErrors) != 0 {
+		return PersonProperties{}, fieldErrors
+	}
+
+	return dto, nil
+}
+
+// parsePersonsCSV parses the CSV with the header row. Invalid data rows are reported in the result,
+// the error is returned only if the CSV itself cannot be parsed.
+func parsePersonsCSV(body []byte) ([]csvPerson, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if stderrors.Is(err, io.EOF) {
+		return nil, errors.ErrInvalidCSV("empty CSV", nil)
+	}
+
+	if err != nil {
+		return nil, errors.ErrInvalidCSV(err.Error(), nil)
+	}
+
+	columns, err := parseCSVHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var persons []csvPerson
+
+	for {
+		record, err := reader.Read()
+		if stderrors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil && !stderrors.Is(err, csv.ErrFieldCount) {
+			return nil, errors.ErrInvalidCSV(err.Error(), nil)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		if err != nil {
+			persons = append(persons, csvPerson{
+				batchItem: batchItem{Errors: map[string]string{"row": "must have " + strconv.Itoa(len(header)) + " fields"}},
+				Line:      line,
+			})
+		} else {
+			dto, fieldErrors := parseCSVRecord(record, columns)
+			persons = append(persons, csvPerson{batchItem: batchItem{Person: dto, Errors: fieldErrors}, Line: line})
+		}
+
+		if len(persons) > maxImportRows {
+			return nil, errors.ErrInvalidCSV("too many rows, at most "+strconv.Itoa(maxImportRows)+" are allowed", nil)
+		}
+	}
+
+	return persons, nil
+}
@@ -0,0 +1,110 @@
+package delivery_test
+
+import (
+	"encoding/csv"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type CSVSuite struct {
+	suite.Suite
+}
+
+func (*CSVSuite) newApp(useCase *UseCaseMock) *fiber.App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New()
+	app.Use(bodystream.Middleware)
+	delivery.AddHandlers(app, delivery.Config{}, useCase, logger)
+
+	return app
+}
+
+func (s *CSVSuite) TestExportEscapesFormulas(t provider.T) {
+	t.Epic("CSV")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	persons := []models.Person{{
+		ID: 1,
+		PersonProperties: models.PersonProperties{
+			Name:    "=HYPERLINK(\"http://evil.example\")",
+			Age:     age(42),
+			Address: "@SUM(A1:A2)",
+			Work:    "-2+3",
+		},
+	}, {
+		ID: 2,
+		PersonProperties: models.PersonProperties{
+			Name: "Aboba",
+			Work: "Engineer - backend",
+		},
+	}}
+	useCase := new(UseCaseMock)
+	useCase.On("StreamPersons", mock.Anything).Return(iter.Seq2[models.Person, error](func(yield func(models.Person, error) bool) {
+		for _, person := range persons {
+			if !yield(person, nil) {
+				return
+			}
+		}
+	}))
+	app := s.newApp(useCase)
+	// act
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/persons/export.csv", nil), -1)
+	t.Require().NoError(err)
+	defer res.Body.Close()
+	records, err := csv.NewReader(res.Body).ReadAll()
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().Equal([][]string{
+		{"id", "name", "age", "address", "work"},
+		{"1", "'=HYPERLINK(\"http://evil.example\")", "42", "'@SUM(A1:A2)", "'-2+3"},
+		{"2", "Aboba", "", "", "Engineer - backend"},
+	}, records)
+	useCase.AssertExpectations(t)
+}
+
+func (s *CSVSuite) TestImportUnescapesFormulas(t provider.T) {
+	t.Epic("CSV")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("CreatePersons", []models.PersonProperties{
+		{Name: "=1+1", Age: age(42), Address: "'Quoted", Work: "+Work"},
+	}).Return([]models.Person{{ID: 1}}, nil)
+	app := s.newApp(useCase)
+	body := "name,age,address,work\n'=1+1,42,'Quoted,'+Work\n"
+	req := httptest.NewRequest(http.MethodPost, "/persons/import", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, "text/csv")
+	// act
+	res, err := app.Test(req, -1)
+	// assert
+	t.Require().NoError(err)
+	t.Require().NoError(res.Body.Close())
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	useCase.AssertExpectations(t)
+}
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(CSVSuite))
+}
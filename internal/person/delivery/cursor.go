@@ -0,0 +1,90 @@
+package delivery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+)
+
+var errCursorSortMismatch = stderrors.New("cursor was issued for another sort order")
+
+// cursorDTO is encoded into an opaque token. It contains only the sort key values of the boundary person,
+// the unknown age is omitted.
+type cursorDTO struct {
+	Backward bool    `json:"b,omitempty"`
+	Sort     string  `json:"s,omitempty"`
+	ID       int     `json:"id"`
+	Name     *string `json:"n,omitempty"`
+	Age      *int    `json:"a,omitempty"`
+	Address  *string `json:"ad,omitempty"`
+	Work     *string `json:"w,omitempty"`
+}
+
+func encodeCursor(cursor *models.Cursor, sort string, keys []models.SortKey) string {
+	if cursor == nil {
+		return ""
+	}
+
+	dto := cursorDTO{
+		Backward: cursor.Backward,
+		Sort:     sort,
+		ID:       cursor.Boundary.ID,
+	}
+
+	for _, key := range keys {
+		switch key.Field {
+		case models.PersonFieldName:
+			dto.Name = &cursor.Boundary.Name
+		case models.PersonFieldAge:
+			dto.Age = cursor.Boundary.Age
+		case models.PersonFieldAddress:
+			dto.Address = &cursor.Boundary.Address
+		case models.PersonFieldWork:
+			dto.Work = &cursor.Boundary.Work
+		}
+	}
+
+	data, _ := json.Marshal(dto) // cannot fail for this structure
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token, sort string) (*models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var dto cursorDTO
+
+	err = json.Unmarshal(data, &dto)
+	if err != nil {
+		return nil, err
+	}
+
+	if dto.Sort != sort {
+		return nil, errCursorSortMismatch
+	}
+
+	cursor := &models.Cursor{
+		Backward: dto.Backward,
+		Boundary: models.Person{ID: dto.ID},
+	}
+
+	if dto.Name != nil {
+		cursor.Boundary.Name = *dto.Name
+	}
+
+	cursor.Boundary.Age = dto.Age
+
+	if dto.Address != nil {
+		cursor.Boundary.Address = *dto.Address
+	}
+
+	if dto.Work != nil {
+		cursor.Boundary.Work = *dto.Work
+	}
+
+	return cursor, nil
+}
@@ -1,85 +1,189 @@
 package delivery
 
 import (
+	"bufio"
 	"context"
+	"encoding/csv"
+	stderrors "errors"
 	"github.com/Inspirate789/ds-lab1/internal/models"
 	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
 	"github.com/gofiber/fiber/v2"
+	"iter"
 	"log/slog"
 	"math"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 type UseCase interface {
 	HealthCheck(ctx context.Context) error
-	GetPersons(ctx context.Context, offset, limit int64) ([]models.Person, error)
+	GetPersons(ctx context.Context, query models.PersonsQuery) (models.PersonsPage, error)
+	StreamPersons(ctx context.Context, query models.PersonsQuery) iter.Seq2[models.Person, error]
 	CreatePerson(ctx context.Context, person models.PersonProperties) (models.Person, error)
-	GetPerson(ctx context.Context, personID int) (models.Person, bool, error)
-	UpdatePerson(ctx context.Context, person models.Person) (models.Person, bool, error)
-	DeletePerson(ctx context.Context, personID int) (bool, error)
+	CreatePersonOnce(ctx context.Context, idempotencyKey string, person models.PersonProperties) (models.IdempotentResult, error)
+	CreatePersons(ctx context.Context, persons []models.PersonProperties) ([]models.Person, error)
+	ApplyBatch(ctx context.Context, operations []models.BatchOperation) ([]models.Person, error)
+	GetPerson(ctx context.Context, personID int) (models.Person, error)
+	UpdatePerson(ctx context.Context, person models.Person, version int64) (models.Person, error)
+	PatchPerson(ctx context.Context, personID int, patch models.PersonPatch, version int64) (models.Person, error)
+	ReplacePerson(ctx context.Context, person models.Person, version int64, createIfAbsent bool) (models.Person, bool, error)
+	DeletePerson(ctx context.Context, personID int, version int64) error
+	RestorePerson(ctx context.Context, personID int) (models.Person, error)
+	GetPersonHistory(ctx context.Context, query models.PersonHistoryQuery) (models.PersonHistoryPage, error)
+	SearchPersons(ctx context.Context, query models.PersonsSearchQuery) (models.PersonsSearchPage, error)
+	SuggestNames(ctx context.Context, query models.NameSuggestionQuery) ([]models.NameSuggestion, error)
+}
+
+type Config struct {
+	AllowCreateOnPut bool `koanf:"allow_create_on_put"`
+	MaxBatchSize     int  `koanf:"max_batch_size"`
 }
 
 type delivery struct {
+	config  Config
 	useCase UseCase
 	logger  *slog.Logger
 }
 
-func AddHandlers(api fiber.Router, useCase UseCase, logger *slog.Logger) {
+func AddHandlers(root fiber.Router, config Config, useCase UseCase, logger *slog.Logger) {
 	handler := &delivery{
+		config:  config,
 		useCase: useCase,
 		logger:  logger,
 	}
 
+	root.Post("/persons\\:batchCreate", handler.BatchCreatePersons)
+	root.Post("/persons\\:batch", handler.ApplyBatch)
+
+	api := root.Group("/persons")
 	api.Get("/", handler.GetPersons)
 	api.Post("/", handler.PostPerson)
+	api.Get("/export.csv", handler.ExportPersons)
+	api.Post("/import", handler.ImportPersons)
+	api.Get("/search", handler.SearchPersons)
+	api.Get("/suggest", handler.SuggestNames)
 
 	api.Get("/:personId", handler.GetPerson)
+	api.Put("/:personId", handler.PutPerson)
 	api.Patch("/:personId", handler.PatchPerson)
 	api.Delete("/:personId", handler.DeletePerson)
+	api.Post("/:personId/restore", handler.RestorePerson)
+	api.Get("/:personId/history", handler.GetPersonHistory)
+}
+
+func (d *delivery) sendValidationError(ctx *fiber.Ctx, err error) error {
+	d.logger.Warn(err.Error())
+
+	var validationErr errors.ValidationError
+	if !stderrors.As(err, &validationErr) {
+		validationErr = errors.ErrInvalidPersonFields(map[string]string{})
+	}
+
+	return problem.Send(ctx, validationErr.Problem())
+}
+
+// sendError sends the problem of the error returned by the use case.
+// Internal errors are returned to the error handler of the server.
+func (d *delivery) sendError(ctx *fiber.Ctx, err error) error {
+	details, ok := errors.FromError(err)
+	if !ok {
+		return err
+	}
+
+	d.logger.Warn(err.Error())
+
+	return problem.Send(ctx, details)
 }
 
 func (d *delivery) GetPersons(ctx *fiber.Ctx) error {
-	offset, err := strconv.ParseInt(ctx.Query("offset"), 10, 64)
+	query, err := parsePersonsQuery(ctx)
 	if err != nil {
-		d.logger.Warn("persons offset not set, use default 0")
-		offset = 0
+		return d.sendValidationError(ctx, err)
+	}
+
+	if ndjsonRequested(ctx) {
+		if query.Keyset {
+			return d.sendValidationError(ctx, errors.ErrInvalidPersonsQuery(map[string]string{
+				"cursor": "is not supported for " + mimeNDJSON,
+			}))
+		}
+
+		return d.sendPersonsStream(ctx, query)
 	}
 
-	limit, err := strconv.ParseInt(ctx.Query("limit"), 10, 64)
+	page, err := d.useCase.GetPersons(ctx.UserContext(), query)
 	if err != nil {
-		d.logger.Warn("persons limit not set, return all persons")
-		limit = math.MaxInt64
+		return d.sendError(ctx, err)
+	}
+
+	if page.Persons == nil {
+		page.Persons = make([]models.Person, 0)
+	}
+
+	dto := NewPersonsPageDTO(page, query, ctx.Query("sort"))
+	setPageLinks(ctx, query, dto)
+
+	if !query.WithTotal && !query.Keyset {
+		return ctx.Status(fiber.StatusOK).JSON(dto.Persons)
 	}
 
-	persons, err := d.useCase.GetPersons(ctx.UserContext(), offset, limit)
+	err = ctx.Status(fiber.StatusOK).JSON(dto)
 	if err != nil {
 		return err
 	}
 
-	if persons == nil {
-		persons = make([]models.Person, 0)
+	if ctx.Accepts(fiber.MIMEApplicationJSON, pageMediaType) == pageMediaType {
+		ctx.Set(fiber.HeaderContentType, pageMediaType)
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(NewPersonsDTO(persons)) // TODO: .Map()
+	return nil
 }
 
 func (d *delivery) PostPerson(ctx *fiber.Ctx) error {
-	var dto PersonProperties
-
-	err := ctx.BodyParser(&dto)
+	dto, err := parsePersonProperties(ctx.Body(), false)
 	if err != nil {
-		personErr := errors.ErrInvalidPerson(err.Error())
-		d.logger.Error(err.Error())
+		return d.sendValidationError(ctx, err)
+	}
 
-		return ctx.Status(fiber.StatusBadRequest).JSON(personErr.Map())
+	if idempotencyKey := ctx.Get(headerIdempotencyKey); idempotencyKey != "" {
+		return d.postPersonOnce(ctx, idempotencyKey, dto)
 	}
 
 	person, err := d.useCase.CreatePerson(ctx.UserContext(), dto.ToProperties())
 	if err != nil {
-		return err
+		return d.sendError(ctx, err)
 	}
 
 	ctx.Location(ctx.Path() + "/" + strconv.Itoa(person.ID))
+	setETag(ctx, person)
+
+	return ctx.SendStatus(fiber.StatusCreated)
+}
+
+const (
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+func (d *delivery) postPersonOnce(ctx *fiber.Ctx, idempotencyKey string, dto PersonProperties) error {
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return problem.Send(ctx, errors.ErrInvalidIdempotencyKey.Problem())
+	}
+
+	res, err := d.useCase.CreatePersonOnce(ctx.UserContext(), idempotencyKey, dto.ToProperties())
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	ctx.Location(ctx.Path() + "/" + strconv.Itoa(res.PersonID))
+
+	if res.Replayed {
+		ctx.Set(headerIdempotentReplayed, "true")
+	}
 
 	return ctx.SendStatus(fiber.StatusCreated)
 }
@@ -87,44 +191,152 @@ func (d *delivery) PostPerson(ctx *fiber.Ctx) error {
 func (d *delivery) GetPerson(ctx *fiber.Ctx) error {
 	personID, err := strconv.Atoi(ctx.Params("personId"))
 	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(errors.ErrInvalidID.Map())
+		return problem.Send(ctx, errors.ErrInvalidID.Problem())
 	}
 
-	person, found, err := d.useCase.GetPerson(ctx.UserContext(), personID)
+	person, err := d.useCase.GetPerson(ctx.UserContext(), personID)
 	if err != nil {
-		return err
+		return d.sendError(ctx, err)
 	}
 
-	if !found {
-		return ctx.Status(fiber.StatusNotFound).JSON(errors.ErrPersonNotFound.Map())
+	setETag(ctx, person)
+
+	if matchesIfNoneMatch(ctx, person) {
+		return ctx.SendStatus(fiber.StatusNotModified)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(NewPersonDTO(person))
 }
 
+func (d *delivery) sendPreconditionFailed(ctx *fiber.Ctx) error {
+	return problem.Send(ctx, errors.ErrPreconditionFailed.Problem())
+}
+
+// expectedVersion returns the version of the person checked by the use case. If the precondition lists several
+// entity tags, the current version is checked if it is listed; the use case checks it again with the change.
+func (d *delivery) expectedVersion(ctx *fiber.Ctx, personID int, condition ifMatch) (int64, error) {
+	switch len(condition.versions) {
+	case 0:
+		return models.AnyVersion, nil
+	case 1:
+		return condition.versions[0], nil
+	}
+
+	person, err := d.useCase.GetPerson(ctx.UserContext(), personID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !slices.Contains(condition.versions, person.Version) {
+		return 0, models.ErrVersionMismatch
+	}
+
+	return person.Version, nil
+}
+
 func (d *delivery) PatchPerson(ctx *fiber.Ctx) error {
 	personID, err := strconv.Atoi(ctx.Params("personId"))
 	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(errors.ErrInvalidID.Map())
+		return problem.Send(ctx, errors.ErrInvalidID.Problem())
 	}
 
-	var dto PersonProperties
+	condition, ok := parseIfMatch(ctx)
+	if !ok {
+		return d.sendPreconditionFailed(ctx)
+	}
 
-	err = ctx.BodyParser(&dto)
+	version, err := d.expectedVersion(ctx, personID, condition)
 	if err != nil {
-		personErr := errors.ErrInvalidPerson(err.Error())
-		d.logger.Error(err.Error())
+		return d.sendError(ctx, err)
+	}
+
+	var person models.Person
+
+	switch mediaType(ctx) {
+	case mimeMergePatchJSON:
+		person, err = d.applyPatch(ctx, personID, version, parseMergePatch)
+	case mimeJSONPatchJSON:
+		person, err = d.applyPatch(ctx, personID, version, parseJSONPatch)
+	default:
+		var dto PersonProperties
 
-		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(personErr.Map())
+		dto, err = parsePersonProperties(ctx.Body(), true)
+		if err != nil {
+			return d.sendValidationError(ctx, err)
+		}
+
+		person, err = d.useCase.UpdatePerson(ctx.UserContext(), dto.ToPerson(personID), version)
 	}
 
-	person, found, err := d.useCase.UpdatePerson(ctx.UserContext(), dto.ToPerson(personID))
 	if err != nil {
-		return err
+		return d.sendError(ctx, err)
 	}
 
-	if !found {
-		return ctx.Status(fiber.StatusNotFound).JSON(errors.ErrPersonNotFound.Map())
+	setETag(ctx, person)
+
+	return ctx.Status(fiber.StatusOK).JSON(NewPersonDTO(person))
+}
+
+func mediaType(ctx *fiber.Ctx) string {
+	contentType, _, _ := strings.Cut(ctx.Get(fiber.HeaderContentType), ";")
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+func (d *delivery) applyPatch(
+	ctx *fiber.Ctx,
+	personID int,
+	version int64,
+	parse func(body []byte) (models.PersonPatch, error),
+) (models.Person, error) {
+	patch, err := parse(ctx.Body())
+	if err != nil {
+		return models.Person{}, err
+	}
+
+	return d.useCase.PatchPerson(ctx.UserContext(), personID, patch, version)
+}
+
+func (d *delivery) PutPerson(ctx *fiber.Ctx) error {
+	personID, err := strconv.Atoi(ctx.Params("personId"))
+	if err != nil || personID <= 0 {
+		return problem.Send(ctx, errors.ErrInvalidID.Problem())
+	}
+
+	condition, ok := parseIfMatch(ctx)
+	if !ok {
+		return d.sendPreconditionFailed(ctx)
+	}
+
+	dto, err := parsePersonProperties(ctx.Body(), false)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	// the person is created only without the precondition, If-Match fails if there is no person (RFC 9110)
+	createIfAbsent := d.config.AllowCreateOnPut && !condition.present()
+
+	version, err := d.expectedVersion(ctx, personID, condition)
+
+	var person models.Person
+	var created bool
+
+	if err == nil {
+		person, created, err = d.useCase.ReplacePerson(ctx.UserContext(), dto.ToPerson(personID), version, createIfAbsent)
+	}
+
+	if condition.present() && stderrors.Is(err, models.ErrPersonNotFound) {
+		return d.sendPreconditionFailed(ctx)
+	}
+
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	setETag(ctx, person)
+
+	if created {
+		ctx.Location(ctx.Path())
+		return ctx.Status(fiber.StatusCreated).JSON(NewPersonDTO(person))
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(NewPersonDTO(person))
@@ -133,17 +345,180 @@ func (d *delivery) PatchPerson(ctx *fiber.Ctx) error {
 func (d *delivery) DeletePerson(ctx *fiber.Ctx) error {
 	personID, err := strconv.Atoi(ctx.Params("personId"))
 	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(errors.ErrInvalidID.Map())
+		return problem.Send(ctx, errors.ErrInvalidID.Problem())
+	}
+
+	condition, ok := parseIfMatch(ctx)
+	if !ok {
+		return d.sendPreconditionFailed(ctx)
 	}
 
-	found, err := d.useCase.DeletePerson(ctx.UserContext(), personID)
+	version, err := d.expectedVersion(ctx, personID, condition)
 	if err != nil {
-		return err
+		return d.sendError(ctx, err)
 	}
 
-	if !found {
-		return ctx.Status(fiber.StatusNotFound).JSON(errors.ErrPersonNotFound.Map())
+	err = d.useCase.DeletePerson(ctx.UserContext(), personID, version)
+	if err != nil {
+		return d.sendError(ctx, err)
 	}
 
 	return ctx.SendStatus(fiber.StatusNoContent)
 }
+
+func (d *delivery) RestorePerson(ctx *fiber.Ctx) error {
+	personID, err := strconv.Atoi(ctx.Params("personId"))
+	if err != nil {
+		return problem.Send(ctx, errors.ErrInvalidID.Problem())
+	}
+
+	person, err := d.useCase.RestorePerson(ctx.UserContext(), personID)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	setETag(ctx, person)
+
+	return ctx.Status(fiber.StatusOK).JSON(NewPersonDTO(person))
+}
+
+func (d *delivery) GetPersonHistory(ctx *fiber.Ctx) error {
+	personID, err := strconv.Atoi(ctx.Params("personId"))
+	if err != nil {
+		return problem.Send(ctx, errors.ErrInvalidID.Problem())
+	}
+
+	query, err := parsePersonHistoryQuery(ctx, personID)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	page, err := d.useCase.GetPersonHistory(ctx.UserContext(), query)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	dto := NewPersonHistoryPageDTO(page, query)
+	setOffsetPageLinks(ctx, query.Offset, query.Limit, dto.HasMore)
+
+	return ctx.Status(fiber.StatusOK).JSON(dto)
+}
+
+// SearchPersons returns the persons matching the full-text query ordered by relevance.
+func (d *delivery) SearchPersons(ctx *fiber.Ctx) error {
+	query, err := parsePersonsSearchQuery(ctx)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	page, err := d.useCase.SearchPersons(ctx.UserContext(), query)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	dto := NewPersonsSearchPageDTO(page, query)
+	setOffsetPageLinks(ctx, query.Offset, query.Limit, dto.HasMore)
+
+	return ctx.Status(fiber.StatusOK).JSON(dto)
+}
+
+// SuggestNames returns the names similar to the given one for the autocomplete.
+func (d *delivery) SuggestNames(ctx *fiber.Ctx) error {
+	query, err := parseNameSuggestionQuery(ctx)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	suggestions, err := d.useCase.SuggestNames(ctx.UserContext(), query)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(NewNameSuggestionsDTO(suggestions))
+}
+
+// ExportPersons streams the persons matching the list filters as CSV with the header row.
+func (d *delivery) ExportPersons(ctx *fiber.Ctx) error {
+	query, err := parsePersonsQuery(ctx)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	// the stream writer is called after the handler returns, so the fiber context must not be used there
+	streamCtx, cancel := streamContext(ctx)
+	query = models.PersonsQuery{Filter: query.Filter, Sort: query.Sort, Limit: math.MaxInt64}
+
+	ctx.Set(fiber.HeaderContentType, mimeTextCSV)
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="persons.csv"`)
+	bodystream.SetWriter(ctx, func(w *bufio.Writer) {
+		defer cancel()
+
+		writer := csv.NewWriter(w)
+
+		err := writer.Write(csvColumns)
+		if err == nil {
+			err = d.streamPersons(streamCtx, query, func(person models.Person) error {
+				return writer.Write(personRecord(person))
+			}, func() error {
+				writer.Flush()
+				if err := writer.Error(); err != nil {
+					return err
+				}
+
+				return w.Flush()
+			})
+		}
+
+		if err != nil {
+			d.logger.Error("failed to export persons", slog.String("error", err.Error()))
+		}
+	})
+
+	return nil
+}
+
+// ImportPersons creates persons from the CSV and reports the result for every row.
+func (d *delivery) ImportPersons(ctx *fiber.Ctx) error {
+	mode, err := parseBatchMode(ctx)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	rows, err := parsePersonsCSV(ctx.Body())
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	items := make([]batchItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.batchItem)
+	}
+
+	persons, created, err := d.createBatch(ctx.UserContext(), items, mode)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	report := ImportReport{
+		Mode: string(mode),
+		Rows: make([]ImportRow, len(rows)),
+	}
+
+	for i, row := range rows {
+		report.Rows[i] = ImportRow{Line: row.Line, Errors: items[i].Errors}
+
+		switch {
+		case items[i].Errors != nil:
+			report.Failed++
+		case created:
+			report.Rows[i].ID = persons[i].ID
+			report.Created++
+		}
+	}
+
+	if !created {
+		return ctx.Status(fiber.StatusBadRequest).JSON(report)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(report)
+}
@@ -1,18 +1,22 @@
 package delivery
 
 import (
+	"encoding/json"
 	"github.com/Inspirate789/ds-lab1/internal/models"
 	"github.com/gofiber/fiber/v2"
+	"math"
+	"time"
 )
 
 type Person struct {
 	ID int `json:"id"`
 	PersonProperties
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type PersonProperties struct {
 	Name    string `json:"name"`
-	Age     int    `json:"age"`
+	Age     *int   `json:"age"`
 	Address string `json:"address"`
 	Work    string `json:"work"`
 }
@@ -26,6 +30,7 @@ func NewPersonDTO(person models.Person) Person {
 			Address: person.Address,
 			Work:    person.Work,
 		},
+		DeletedAt: person.DeletedAt,
 	}
 }
 
@@ -68,3 +73,340 @@ func (p Persons) Map() map[string]any {
 		"count":   len(p),
 	}
 }
+
+type PersonsPage struct {
+	Persons    Persons `json:"persons"`
+	Count      int     `json:"count"`
+	Total      *int64  `json:"total,omitempty"`
+	Offset     *int64  `json:"offset,omitempty"`
+	Limit      *int64  `json:"limit,omitempty"`
+	HasMore    bool    `json:"has_more"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+func NewPersonsPageDTO(page models.PersonsPage, query models.PersonsQuery, sort string) PersonsPage {
+	dto := PersonsPage{
+		Persons: NewPersonsDTO(page.Persons),
+		Count:   len(page.Persons),
+		HasMore: page.HasMore,
+	}
+
+	if query.WithTotal {
+		dto.Total = &page.Total
+	}
+
+	if query.Limit != math.MaxInt64 {
+		dto.Limit = &query.Limit
+	}
+
+	if query.Keyset {
+		dto.HasMore = page.NextCursor != nil
+		dto.NextCursor = encodeCursor(page.NextCursor, sort, query.Sort)
+		dto.PrevCursor = encodeCursor(page.PrevCursor, sort, query.Sort)
+	} else {
+		dto.Offset = &query.Offset
+	}
+
+	return dto
+}
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+type PersonHistoryEntry struct {
+	ID        int64         `json:"id"`
+	Version   int64         `json:"version"`
+	Operation string        `json:"operation"`
+	ChangedAt time.Time     `json:"changed_at"`
+	Actor     string        `json:"actor,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Before    *Person       `json:"before"`
+	After     *Person       `json:"after"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+func newPersonSnapshotDTO(person *models.Person) *Person {
+	if person == nil {
+		return nil
+	}
+
+	dto := NewPersonDTO(*person)
+
+	return &dto
+}
+
+func NewPersonHistoryEntryDTO(entry models.PersonHistoryEntry) PersonHistoryEntry {
+	changes := entry.Changes()
+	dto := PersonHistoryEntry{
+		ID:        entry.ID,
+		Version:   entry.Version,
+		Operation: string(entry.Operation),
+		ChangedAt: entry.ChangedAt,
+		Actor:     entry.Actor,
+		RequestID: entry.RequestID,
+		Before:    newPersonSnapshotDTO(entry.Before),
+		After:     newPersonSnapshotDTO(entry.After),
+		Changes:   make([]FieldChange, 0, len(changes)),
+	}
+
+	for _, change := range changes {
+		dto.Changes = append(dto.Changes, FieldChange(change))
+	}
+
+	return dto
+}
+
+type PersonHistoryPage struct {
+	Entries []PersonHistoryEntry `json:"entries"`
+	Offset  int64                `json:"offset"`
+	Limit   int64                `json:"limit"`
+	HasMore bool                 `json:"has_more"`
+}
+
+func NewPersonHistoryPageDTO(page models.PersonHistoryPage, query models.PersonHistoryQuery) PersonHistoryPage {
+	dto := PersonHistoryPage{
+		Entries: make([]PersonHistoryEntry, 0, len(page.Entries)),
+		Offset:  query.Offset,
+		Limit:   query.Limit,
+		HasMore: page.HasMore,
+	}
+
+	for _, entry := range page.Entries {
+		dto.Entries = append(dto.Entries, NewPersonHistoryEntryDTO(entry))
+	}
+
+	return dto
+}
+
+type PersonSearchResult struct {
+	Person     Person            `json:"person"`
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type PersonsSearchPage struct {
+	Results []PersonSearchResult `json:"results"`
+	Offset  int64                `json:"offset"`
+	Limit   int64                `json:"limit"`
+	HasMore bool                 `json:"has_more"`
+}
+
+func NewPersonsSearchPageDTO(page models.PersonsSearchPage, query models.PersonsSearchQuery) PersonsSearchPage {
+	dto := PersonsSearchPage{
+		Results: make([]PersonSearchResult, 0, len(page.Results)),
+		Offset:  query.Offset,
+		Limit:   query.Limit,
+		HasMore: page.HasMore,
+	}
+
+	for _, result := range page.Results {
+		highlights := make(map[string]string, len(result.Highlights))
+		for field, highlight := range result.Highlights {
+			highlights[string(field)] = highlight
+		}
+
+		dto.Results = append(dto.Results, PersonSearchResult{
+			Person:     NewPersonDTO(result.Person),
+			Rank:       result.Rank,
+			Highlights: highlights,
+		})
+	}
+
+	return dto
+}
+
+type NameSuggestion struct {
+	Name       string  `json:"name"`
+	Similarity float64 `json:"similarity"`
+}
+
+type NameSuggestions struct {
+	Suggestions []NameSuggestion `json:"suggestions"`
+}
+
+func NewNameSuggestionsDTO(suggestions []models.NameSuggestion) NameSuggestions {
+	dto := NameSuggestions{
+		Suggestions: make([]NameSuggestion, 0, len(suggestions)),
+	}
+
+	for _, suggestion := range suggestions {
+		dto.Suggestions = append(dto.Suggestions, NameSuggestion(suggestion))
+	}
+
+	return dto
+}
+
+type ImportRow struct {
+	Line   int               `json:"line"`
+	ID     int               `json:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	Mode    string      `json:"mode"`
+	Created int         `json:"created"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+type BatchCreateResult struct {
+	Index    int               `json:"index"`
+	ID       int               `json:"id,omitempty"`
+	Location string            `json:"location,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+type BatchCreateReport struct {
+	Mode    string              `json:"mode"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Results []BatchCreateResult `json:"results"`
+}
+
+type BatchOperationResult struct {
+	Index    int               `json:"index"`
+	Op       string            `json:"op"`
+	Status   int               `json:"status"`
+	ID       int               `json:"id,omitempty"`
+	Location string            `json:"location,omitempty"`
+	ETag     string            `json:"etag,omitempty"`
+	Person   *Person           `json:"person,omitempty"`
+	Message  string            `json:"message,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+type BatchReport struct {
+	Committed bool                   `json:"committed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+type WebhookProperties struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+// Webhook is the subscription without the secret, which is sent only once, when the webhook is created.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreatedWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+func NewWebhookDTO(webhook models.Webhook) Webhook {
+	events := make([]string, 0, len(webhook.Events))
+	for _, event := range webhook.Events {
+		events = append(events, string(event))
+	}
+
+	return Webhook{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    events,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+func NewWebhooksDTO(webhooks []models.Webhook) []Webhook {
+	dto := make([]Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		dto = append(dto, NewWebhookDTO(webhook))
+	}
+
+	return dto
+}
+
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	LastStatus    int             `json:"last_status,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func NewWebhookDeliveryDTO(delivery models.WebhookDelivery) WebhookDelivery {
+	dto := WebhookDelivery{
+		ID:          delivery.ID,
+		EventID:     delivery.EventID,
+		EventType:   string(delivery.EventType),
+		Payload:     delivery.Payload,
+		Status:      string(delivery.Status),
+		Attempts:    delivery.Attempts,
+		LastError:   delivery.LastError,
+		LastStatus:  delivery.LastStatus,
+		CreatedAt:   delivery.CreatedAt,
+		DeliveredAt: delivery.DeliveredAt,
+	}
+
+	if delivery.Status == models.DeliveryPending {
+		dto.NextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return dto
+}
+
+type WebhookDeliveriesPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Offset     int64             `json:"offset"`
+	Limit      int64             `json:"limit"`
+	HasMore    bool              `json:"has_more"`
+}
+
+func NewWebhookDeliveriesPageDTO(
+	page models.WebhookDeliveriesPage,
+	query models.WebhookDeliveriesQuery,
+) WebhookDeliveriesPage {
+	dto := WebhookDeliveriesPage{
+		Deliveries: make([]WebhookDelivery, 0, len(page.Deliveries)),
+		Offset:     query.Offset,
+		Limit:      query.Limit,
+		HasMore:    page.HasMore,
+	}
+
+	for _, delivery := range page.Deliveries {
+		dto.Deliveries = append(dto.Deliveries, NewWebhookDeliveryDTO(delivery))
+	}
+
+	return dto
+}
+
+// PersonEvent is the data of the event of the change feed. The person is omitted from the deleted events.
+type PersonEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	PersonID   int       `json:"person_id"`
+	Person     *Person   `json:"person,omitempty"`
+}
+
+func NewPersonEventDTO(event models.PersonEvent) PersonEvent {
+	dto := PersonEvent{
+		ID:         event.ID,
+		Type:       string(event.Type),
+		OccurredAt: event.OccurredAt.UTC(),
+		PersonID:   event.Person.ID,
+	}
+
+	if event.Type != models.PersonDeleted {
+		person := NewPersonDTO(event.Person)
+		dto.Person = &person
+	}
+
+	return dto
+}
@@ -1,6 +1,50 @@
 package errors
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+)
+
+// problemTypePrefix identifies the problem types of the service.
+const problemTypePrefix = "urn:problem-type:persons:"
+
+type problemType struct {
+	name   string
+	title  string
+	status int
+}
+
+func (t problemType) problem(detail string) problem.Details {
+	return problem.Details{
+		Type:   problemTypePrefix + t.name,
+		Title:  t.title,
+		Status: t.status,
+		Detail: detail,
+	}
+}
+
+var (
+	invalidRequestProblem = problemType{"invalid-request", "Request validation failed", fiber.StatusBadRequest}
+	conflictProblem       = problemType{"conflict", "Conflicting change", fiber.StatusConflict}
+	unavailableProblem    = problemType{"unavailable", "Service unavailable", fiber.StatusServiceUnavailable}
+
+	personProblems = map[PersonError]problemType{
+		ErrInvalidID:             {"invalid-id", "Invalid person ID", fiber.StatusBadRequest},
+		ErrPersonNotFound:        {"person-not-found", "Person not found", fiber.StatusNotFound},
+		ErrPatchTestFailed:       {"patch-test-failed", "Patch test failed", fiber.StatusConflict},
+		ErrPreconditionFailed:    {"precondition-failed", "Person version mismatch", fiber.StatusPreconditionFailed},
+		ErrInvalidIdempotencyKey: {"invalid-idempotency-key", "Invalid idempotency key", fiber.StatusBadRequest},
+		ErrIdempotencyKeyReused:  {"idempotency-key-reused", "Idempotency key reused", fiber.StatusUnprocessableEntity},
+		ErrDeletedPersonNotFound: {"deleted-person-not-found", "Deleted person not found", fiber.StatusNotFound},
+		ErrPersonDeleted:         {"person-deleted", "Person deleted", fiber.StatusConflict},
+		ErrInvalidWebhookID:      {"invalid-webhook-id", "Invalid webhook ID", fiber.StatusBadRequest},
+		ErrWebhookNotFound:       {"webhook-not-found", "Webhook not found", fiber.StatusNotFound},
+		ErrForbiddenWebhookURL:   {"forbidden-webhook-url", "Forbidden webhook URL", fiber.StatusBadRequest},
+	}
+)
 
 type PersonError string
 
@@ -8,15 +52,210 @@ func (e PersonError) Error() string {
 	return string(e)
 }
 
-func (e PersonError) Map() map[string]any {
-	return fiber.Map{"message": string(e)}
+// Problem returns the problem details of the error with the status of the error.
+func (e PersonError) Problem() problem.Details {
+	t, ok := personProblems[e]
+	if !ok {
+		t = invalidRequestProblem
+	}
+
+	return t.problem(string(e))
 }
 
 const (
-	ErrInvalidID      PersonError = "invalid person ID"
-	ErrPersonNotFound PersonError = "person not found"
+	ErrInvalidID       PersonError = "invalid person ID"
+	ErrPersonNotFound  PersonError = "person not found"
+	ErrPatchTestFailed PersonError = "JSON patch test operation failed"
+
+	ErrPreconditionFailed PersonError = "person was modified by another request"
+
+	ErrInvalidIdempotencyKey PersonError = "idempotency key is too long"
+	ErrIdempotencyKeyReused  PersonError = "idempotency key was used for another request"
+
+	ErrDeletedPersonNotFound PersonError = "deleted person not found"
+	ErrPersonDeleted         PersonError = "person is deleted, restore it before replacing"
+
+	ErrInvalidBatchOperation PersonError = "invalid batch operation"
+
+	ErrInvalidWebhookID    PersonError = "invalid webhook ID"
+	ErrWebhookNotFound     PersonError = "webhook not found"
+	ErrForbiddenWebhookURL PersonError = "webhook URL must resolve to public addresses"
 )
 
 func ErrInvalidPerson(msg string) PersonError {
 	return PersonError("cannot parse person from request body: " + msg)
 }
+
+type ValidationError struct {
+	Message string
+	Errors  map[string]string
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+// Problem returns the problem details with the errors of the fields as the extension member.
+func (e ValidationError) Problem() problem.Details {
+	errs := e.Errors
+	if errs == nil {
+		errs = make(map[string]string)
+	}
+
+	return invalidRequestProblem.problem(e.Message).With("errors", errs)
+}
+
+func ErrInvalidPersonFields(errs map[string]string) ValidationError {
+	return ValidationError{
+		Message: "invalid person data",
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidPersonsQuery(errs map[string]string) ValidationError {
+	return ValidationError{
+		Message: "invalid persons query parameters",
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidPatch(msg string, errs map[string]string) ValidationError {
+	if errs == nil {
+		errs = make(map[string]string)
+	}
+
+	return ValidationError{
+		Message: "cannot parse patch from request body: " + msg,
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidHistoryQuery(errs map[string]string) ValidationError {
+	return ValidationError{
+		Message: "invalid person history query parameters",
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidCSV(msg string, errs map[string]string) ValidationError {
+	if errs == nil {
+		errs = make(map[string]string)
+	}
+
+	return ValidationError{
+		Message: "cannot parse persons CSV: " + msg,
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidBatch(msg string) ValidationError {
+	return ValidationError{
+		Message: "invalid batch: " + msg,
+		Errors:  make(map[string]string),
+	}
+}
+
+func ErrInvalidWebhook(msg string, errs map[string]string) ValidationError {
+	if errs == nil {
+		errs = make(map[string]string)
+	}
+
+	return ValidationError{
+		Message: "cannot parse webhook from request body: " + msg,
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidWebhookFields(errs map[string]string) ValidationError {
+	return ValidationError{
+		Message: "invalid webhook data",
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidDeliveriesQuery(errs map[string]string) ValidationError {
+	return ValidationError{
+		Message: "invalid webhook deliveries query parameters",
+		Errors:  errs,
+	}
+}
+
+func ErrInvalidGraphQLRequest(msg string) ValidationError {
+	return ValidationError{
+		Message: "cannot parse GraphQL request from request body: " + msg,
+		Errors:  make(map[string]string),
+	}
+}
+
+// ErrSpecViolation is the error of the request which does not match the OpenAPI specification of the service.
+func ErrSpecViolation(errs map[string]string) ValidationError {
+	return ValidationError{
+		Message: "request does not match the API specification",
+		Errors:  errs,
+	}
+}
+
+// modelError is the domain error with its own problem.
+type modelError struct {
+	err     error
+	problem PersonError
+}
+
+// modelErrors are matched in order, so the error wrapping several domain errors always gets the same problem.
+var modelErrors = []modelError{
+	{models.ErrPersonNotFound, ErrPersonNotFound},
+	{models.ErrVersionMismatch, ErrPreconditionFailed},
+	{models.ErrIdempotencyKeyReused, ErrIdempotencyKeyReused},
+	{models.ErrDeletedPersonNotFound, ErrDeletedPersonNotFound},
+	{models.ErrPersonDeleted, ErrPersonDeleted},
+	{models.ErrWebhookNotFound, ErrWebhookNotFound},
+	{models.ErrForbiddenWebhookURL, ErrForbiddenWebhookURL},
+	{models.ErrPatchTestFailed, ErrPatchTestFailed},
+}
+
+// FromError returns the problem of the error returned by the parsers or the use cases.
+// The domain errors are matched first, the rest are mapped by their kind. The causes of the kinds are not
+// exposed to the client. The returned flag is false for internal errors, which are handled by the server.
+func FromError(err error) (problem.Details, bool) {
+	var validationErr ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Problem(), true
+	}
+
+	var personErr PersonError
+	if errors.As(err, &personErr) {
+		return personErr.Problem(), true
+	}
+
+	var fieldErrors models.FieldErrors
+	if errors.As(err, &fieldErrors) {
+		return ErrInvalidPersonFields(fieldErrors).Problem(), true
+	}
+
+	for _, modelErr := range modelErrors {
+		if errors.Is(err, modelErr.err) {
+			return modelErr.problem.Problem(), true
+		}
+	}
+
+	if errors.Is(err, models.ErrInvalidBatchRef) {
+		return ValidationError{
+			Message: ErrInvalidBatchOperation.Error(),
+			Errors:  map[string]string{"ref": models.ErrInvalidBatchRef.Error()},
+		}.Problem(), true
+	}
+
+	switch usecase.KindOf(err) {
+	case usecase.KindNotFound:
+		return ErrPersonNotFound.Problem(), true
+	case usecase.KindConflict:
+		return conflictProblem.problem("request conflicts with a concurrent change, retry it"), true
+	case usecase.KindValidation:
+		return invalidRequestProblem.problem("person data violates the constraints of the storage").
+			With("errors", make(map[string]string)), true
+	case usecase.KindUnavailable:
+		return unavailableProblem.problem("storage is temporarily unavailable, retry later"), true
+	default:
+		return problem.Details{}, false
+	}
+}
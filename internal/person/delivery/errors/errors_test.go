@@ -0,0 +1,43 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"net/http"
+	"testing"
+)
+
+type ErrorsSuite struct {
+	suite.Suite
+}
+
+func (*ErrorsSuite) TestSeveralModelErrors(t provider.T) {
+	t.Epic("Errors")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	err := stderrors.Join(models.ErrPatchTestFailed, models.ErrPersonNotFound)
+
+	for range 100 {
+		// act
+		details, ok := errors.FromError(err)
+		// assert
+		t.Require().True(ok)
+		t.Require().Equal(http.StatusNotFound, details.Status)
+		t.Require().Equal(errors.ErrPersonNotFound.Error(), details.Detail)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(ErrorsSuite))
+}
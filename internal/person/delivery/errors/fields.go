@@ -0,0 +1,23 @@
+package errors
+
+import "fmt"
+
+// The messages of the invalid fields are shared by the handlers and the validation of the OpenAPI specification,
+// so the request gets the same errors whichever of them rejects it.
+const (
+	FieldRequired = "is required"
+	FieldUnknown  = "unknown field"
+)
+
+func FieldTooLong(maxLength int) string {
+	return fmt.Sprintf("must be at most %d characters long", maxLength)
+}
+
+func FieldOutOfRange(minValue, maxValue int) string {
+	return fmt.Sprintf("must be between %d and %d", minValue, maxValue)
+}
+
+// FieldWrongType returns the message of the value of another JSON type, e.g. "an integer".
+func FieldWrongType(typeName string) string {
+	return "must be " + typeName
+}
@@ -0,0 +1,103 @@
+package delivery
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+)
+
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(ctx *fiber.Ctx, person models.Person) {
+	ctx.Set(fiber.HeaderETag, formatETag(person.Version))
+}
+
+// ifMatch is the precondition of the If-Match header (RFC 9110).
+type ifMatch struct {
+	// exists is set by "*", which matches any version of the existing person.
+	exists bool
+	// versions are the versions of the listed entity tags, one of them must be the current version.
+	versions []int64
+}
+
+// present reports whether the header is set, so the person must exist.
+func (m ifMatch) present() bool {
+	return m.exists || len(m.versions) != 0
+}
+
+// parseIfMatch returns the precondition of the If-Match header, which is "*" or the list of strong entity tags.
+func parseIfMatch(ctx *fiber.Ctx) (ifMatch, bool) {
+	header := strings.TrimSpace(ctx.Get(fiber.HeaderIfMatch))
+
+	switch header {
+	case "":
+		return ifMatch{}, true
+	case "*":
+		return ifMatch{exists: true}, true
+	}
+
+	tags := strings.Split(header, ",")
+	versions := make([]int64, 0, len(tags))
+
+	for _, tag := range tags {
+		version, ok := parseEntityTag(strings.TrimSpace(tag))
+		if !ok {
+			return ifMatch{}, false
+		}
+
+		versions = append(versions, version)
+	}
+
+	return ifMatch{versions: versions}, true
+}
+
+// parseExpectedVersion returns the person version expected by the single entity tag, e.g. of the batch operation.
+// Empty value and "*" match any version.
+func parseExpectedVersion(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "*" {
+		return models.AnyVersion, true
+	}
+
+	return parseEntityTag(value)
+}
+
+// parseEntityTag returns the person version of the entity tag. Only strong entity tags are accepted (RFC 9110).
+func parseEntityTag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version == models.AnyVersion {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// matchesIfNoneMatch reports whether the If-None-Match header contains the entity tag of the person.
+// The comparison is weak as required for If-None-Match (RFC 9110).
+func matchesIfNoneMatch(ctx *fiber.Ctx, person models.Person) bool {
+	header := strings.TrimSpace(ctx.Get(fiber.HeaderIfNoneMatch))
+	if header == "" {
+		return false
+	}
+
+	if header == "*" {
+		return true
+	}
+
+	etag := formatETag(person.Version)
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,194 @@
+package delivery_test
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type IfMatchSuite struct {
+	suite.Suite
+}
+
+func (*IfMatchSuite) newPerson() models.Person {
+	return models.Person{
+		ID:      1,
+		Version: 3,
+		PersonProperties: models.PersonProperties{
+			Name: "Aboba",
+			Age:  age(42),
+		},
+	}
+}
+
+// send sends the request with the If-Match header to the handlers allowing to create persons by PUT.
+func (s *IfMatchSuite) send(t provider.T, useCase *UseCaseMock, method, ifMatch string) *http.Response {
+	return s.sendWithConfig(t, delivery.Config{AllowCreateOnPut: true}, useCase, method, ifMatch)
+}
+
+func (*IfMatchSuite) sendWithConfig(
+	t provider.T,
+	config delivery.Config,
+	useCase *UseCaseMock,
+	method, ifMatch string,
+) *http.Response {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New()
+	delivery.AddHandlers(app, config, useCase, logger)
+
+	req := httptest.NewRequest(method, "/persons/1", strings.NewReader(`{"name": "Aboba", "age": 42}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	if ifMatch != "" {
+		req.Header.Set(fiber.HeaderIfMatch, ifMatch)
+	}
+
+	res, err := app.Test(req, -1)
+	t.Require().NoError(err)
+	t.Require().NoError(res.Body.Close())
+
+	return res
+}
+
+func (s *IfMatchSuite) TestPutCreatesWithoutPrecondition(t provider.T) {
+	t.Epic("Preconditions")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	person := s.newPerson()
+	useCase := new(UseCaseMock)
+	useCase.On("ReplacePerson", mock.Anything, models.AnyVersion, true).Return(person, true, nil)
+	// act
+	res := s.send(t, useCase, http.MethodPut, "")
+	// assert
+	t.Require().Equal(http.StatusCreated, res.StatusCode)
+	useCase.AssertExpectations(t)
+}
+
+// createOnPutModes are the configurations of the handlers the preconditions must not depend on.
+var createOnPutModes = map[string]delivery.Config{
+	"create on PUT":    {AllowCreateOnPut: true},
+	"no create on PUT": {AllowCreateOnPut: false},
+}
+
+func (s *IfMatchSuite) TestPutAnyVersionOfMissingPerson(t provider.T) {
+	t.Epic("Preconditions")
+	t.Severity(allure.CRITICAL)
+
+	for name, config := range createOnPutModes {
+		t.Run(name, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			useCase.On("ReplacePerson", mock.Anything, models.AnyVersion, false).
+				Return(models.Person{}, false, usecase.NotFound(models.ErrPersonNotFound))
+			// act
+			res := s.sendWithConfig(t, config, useCase, http.MethodPut, "*")
+			// assert
+			t.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func (s *IfMatchSuite) TestPutVersionOfMissingPerson(t provider.T) {
+	t.Epic("Preconditions")
+	t.Severity(allure.CRITICAL)
+
+	for name, config := range createOnPutModes {
+		t.Run(name, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			useCase.On("ReplacePerson", mock.Anything, int64(3), false).
+				Return(models.Person{}, false, usecase.NotFound(models.ErrPersonNotFound))
+			// act
+			res := s.sendWithConfig(t, config, useCase, http.MethodPut, `"3"`)
+			// assert
+			t.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func (s *IfMatchSuite) TestPutListedVersionsOfMissingPerson(t provider.T) {
+	t.Epic("Preconditions")
+	t.Severity(allure.NORMAL)
+
+	for name, config := range createOnPutModes {
+		t.Run(name, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			useCase.On("GetPerson", 1).Return(models.Person{}, usecase.NotFound(models.ErrPersonNotFound))
+			// act
+			res := s.sendWithConfig(t, config, useCase, http.MethodPut, `"1", "3"`)
+			// assert
+			t.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+			useCase.AssertNotCalled(t, "ReplacePerson", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func (s *IfMatchSuite) TestDeleteMatchingListedVersion(t provider.T) {
+	t.Epic("Preconditions")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	person := s.newPerson()
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", person.ID).Return(person, nil)
+	useCase.On("DeletePerson", person.ID, person.Version).Return(nil)
+	// act
+	res := s.send(t, useCase, http.MethodDelete, `"1", "3"`)
+	// assert
+	t.Require().Equal(http.StatusNoContent, res.StatusCode)
+	useCase.AssertExpectations(t)
+}
+
+func (s *IfMatchSuite) TestDeleteNotListedVersion(t provider.T) {
+	t.Epic("Preconditions")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	person := s.newPerson()
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", person.ID).Return(person, nil)
+	// act
+	res := s.send(t, useCase, http.MethodDelete, `"1","2"`)
+	// assert
+	t.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+	useCase.AssertNotCalled(t, "DeletePerson", mock.Anything, mock.Anything)
+}
+
+func (s *IfMatchSuite) TestWeakEntityTag(t provider.T) {
+	t.Epic("Preconditions")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	// act
+	res := s.send(t, useCase, http.MethodDelete, `"1", W/"3"`)
+	// assert
+	t.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+	useCase.AssertNotCalled(t, "GetPerson", mock.Anything)
+	useCase.AssertNotCalled(t, "DeletePerson", mock.Anything, mock.Anything)
+}
+
+func TestIfMatch(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(IfMatchSuite))
+}
@@ -0,0 +1,473 @@
+package delivery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	mimeEventStream = "text/event-stream"
+	headerLastEvent = "Last-Event-ID"
+
+	defaultPollInterval      = time.Second
+	defaultReplayLimit       = 1000
+	defaultHeartbeatInterval = 15 * time.Second
+	// pollBatchSize limits the events read from the outbox at once.
+	pollBatchSize = 100
+	// subscriberBufferSize is the number of the events waiting for the slow client. The client is disconnected
+	// if it is exceeded, it receives the missed events from the outbox after the reconnection.
+	subscriberBufferSize = 64
+	// eventStreamRetry is the reconnection delay of the clients in milliseconds.
+	eventStreamRetry = 3000
+)
+
+var errEventFeedClosed = stderrors.New("event feed is closed")
+
+// EventSource is the outbox of the person events. The feed of every instance of the service follows it,
+// so the clients receive the changes made through all instances.
+type EventSource interface {
+	GetOutboxEvents(ctx context.Context, afterSequence int64, limit int) ([]models.OutboxEvent, error)
+	GetOutboxBounds(ctx context.Context) (first, last int64, err error)
+}
+
+type EventsConfig struct {
+	// PollInterval is the interval of reading the new events from the outbox.
+	PollInterval time.Duration `koanf:"poll_interval"`
+	// ReplayLimit is the maximal number of the missed events sent to the reconnected clients.
+	ReplayLimit int `koanf:"replay_limit"`
+	// HeartbeatInterval is the interval of the comments keeping the idle streams open.
+	// The disconnected clients are detected by them.
+	HeartbeatInterval time.Duration `koanf:"heartbeat_interval"`
+}
+
+func (c EventsConfig) withDefaults() EventsConfig {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+
+	if c.ReplayLimit <= 0 {
+		c.ReplayLimit = defaultReplayLimit
+	}
+
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = defaultHeartbeatInterval
+	}
+
+	return c
+}
+
+// feedEvent is the encoded person event, its ID is the sequence of the event in the outbox.
+type feedEvent struct {
+	id       int64
+	personID int
+	name     string
+	data     []byte
+}
+
+func newFeedEvent(event models.OutboxEvent) (feedEvent, error) {
+	data, err := json.Marshal(NewPersonEventDTO(event.PersonEvent))
+	if err != nil {
+		return feedEvent{}, err
+	}
+
+	return feedEvent{
+		id:       event.Sequence,
+		personID: event.Person.ID,
+		name:     string(event.Type),
+		data:     data,
+	}, nil
+}
+
+type feedSubscriber struct {
+	personIDs map[int]struct{}
+	// after is the ID of the last event the client has, the earlier events are not sent to it.
+	after  int64
+	events chan feedEvent
+}
+
+func (s *feedSubscriber) matches(event feedEvent) bool {
+	if len(s.personIDs) == 0 {
+		return true
+	}
+
+	_, ok := s.personIDs[event.personID]
+
+	return ok
+}
+
+func (s *feedSubscriber) accepts(event feedEvent) bool {
+	return event.id > s.after && s.matches(event)
+}
+
+// EventFeed broadcasts the person events of the outbox to the clients of the change feed.
+// The missed events are replayed to the reconnected clients from the outbox.
+type EventFeed struct {
+	config EventsConfig
+	source EventSource
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// lastID is the ID of the last event broadcast to the subscribers.
+	lastID      int64
+	subscribers map[*feedSubscriber]struct{}
+	done        chan struct{}
+}
+
+// NewEventFeed returns the feed of the events stored after its creation.
+func NewEventFeed(ctx context.Context, config EventsConfig, source EventSource, logger *slog.Logger) (*EventFeed, error) {
+	_, last, err := source.GetOutboxBounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventFeed{
+		config:      config.withDefaults(),
+		source:      source,
+		logger:      logger,
+		lastID:      last,
+		subscribers: make(map[*feedSubscriber]struct{}),
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// Run reads the new events from the outbox periodically and broadcasts them until the context is canceled
+// or the feed is closed.
+func (f *EventFeed) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.done:
+			return
+		case <-ticker.C:
+			err := f.poll(ctx)
+			if err != nil && ctx.Err() == nil {
+				f.logger.Error("failed to read person events", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// poll broadcasts the events stored since the last poll by batches.
+func (f *EventFeed) poll(ctx context.Context) error {
+	for {
+		f.mu.Lock()
+		lastID := f.lastID
+		f.mu.Unlock()
+
+		events, err := f.source.GetOutboxEvents(ctx, lastID, pollBatchSize)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		f.broadcast(events)
+
+		if len(events) < pollBatchSize {
+			return nil
+		}
+	}
+}
+
+// broadcast sends the events to the subscribers. The slow clients are disconnected instead of blocking the feed.
+func (f *EventFeed) broadcast(events []models.OutboxEvent) {
+	feedEvents := make([]feedEvent, 0, len(events))
+
+	for _, event := range events {
+		feedEvent, err := newFeedEvent(event)
+		if err != nil {
+			f.logger.Error("failed to encode person event", slog.String("error", err.Error()))
+			continue
+		}
+
+		feedEvents = append(feedEvents, feedEvent)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastID = events[len(events)-1].Sequence
+
+	for _, event := range feedEvents {
+		for subscriber := range f.subscribers {
+			if !subscriber.accepts(event) {
+				continue
+			}
+
+			select {
+			case subscriber.events <- event:
+			default:
+				delete(f.subscribers, subscriber)
+				close(subscriber.events)
+			}
+		}
+	}
+}
+
+// subscribe returns the subscriber of the events and the events after the last received one.
+// The returned flag is false if the missed events are not replayed, because they are removed
+// from the outbox or there are too many of them.
+func (f *EventFeed) subscribe(
+	ctx context.Context,
+	personIDs map[int]struct{},
+	lastEventID *int64,
+) (*feedSubscriber, []feedEvent, bool, error) {
+	f.mu.Lock()
+
+	select {
+	case <-f.done:
+		f.mu.Unlock()
+		return nil, nil, false, errEventFeedClosed
+	default:
+	}
+
+	subscriber := &feedSubscriber{
+		personIDs: personIDs,
+		after:     f.lastID,
+		events:    make(chan feedEvent, subscriberBufferSize),
+	}
+	// the client may have received the events from another instance, which has not read them yet
+	if lastEventID != nil && *lastEventID > subscriber.after {
+		subscriber.after = *lastEventID
+	}
+	f.subscribers[subscriber] = struct{}{}
+	lastID := f.lastID
+
+	f.mu.Unlock()
+
+	if lastEventID == nil || *lastEventID >= lastID {
+		return subscriber, nil, true, nil
+	}
+
+	replay, replayed, err := f.replay(ctx, subscriber, *lastEventID, lastID)
+	if err != nil {
+		f.unsubscribe(subscriber)
+		return nil, nil, false, err
+	}
+
+	return subscriber, replay, replayed, nil
+}
+
+// replay returns the events of the subscriber after lastEventID up to the last broadcast one,
+// the later events are sent to the subscriber by the broadcast.
+func (f *EventFeed) replay(
+	ctx context.Context,
+	subscriber *feedSubscriber,
+	lastEventID, lastID int64,
+) ([]feedEvent, bool, error) {
+	first, _, err := f.source.GetOutboxBounds(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if lastEventID < first-1 {
+		return nil, false, nil
+	}
+
+	events, err := f.source.GetOutboxEvents(ctx, lastEventID, f.config.ReplayLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var replay []feedEvent
+
+	for i, event := range events {
+		if event.Sequence > lastID {
+			break
+		}
+
+		if i == f.config.ReplayLimit {
+			return nil, false, nil
+		}
+
+		feedEvent, err := newFeedEvent(event)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if subscriber.matches(feedEvent) {
+			replay = append(replay, feedEvent)
+		}
+	}
+
+	return replay, true, nil
+}
+
+func (f *EventFeed) unsubscribe(subscriber *feedSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[subscriber]; ok {
+		delete(f.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+// Close ends the streams of the clients, so they do not block the shutdown of the server.
+func (f *EventFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	select {
+	case <-f.done:
+	default:
+		close(f.done)
+	}
+}
+
+func AddEventHandlers(root fiber.Router, feed *EventFeed, logger *slog.Logger) {
+	handler := &eventsDelivery{
+		delivery: &delivery{logger: logger},
+		feed:     feed,
+	}
+
+	root.Get("/persons/events", handler.GetPersonEvents)
+}
+
+type eventsDelivery struct {
+	*delivery
+	feed *EventFeed
+}
+
+func parsePersonEventsQuery(ctx *fiber.Ctx) (map[int]struct{}, *int64, error) {
+	fieldErrors := make(map[string]string)
+	personIDs := make(map[int]struct{})
+
+	for _, value := range ctx.Context().QueryArgs().PeekMulti("person_id") {
+		personID, err := strconv.Atoi(string(value))
+		if err != nil || personID <= 0 {
+			fieldErrors["person_id"] = "must be a positive integer"
+			break
+		}
+
+		personIDs[personID] = struct{}{}
+	}
+
+	var lastEventID *int64
+
+	// EventSource cannot set the header of the first request, so the ID may be sent in the query instead
+	if value := ctx.Get(headerLastEvent, ctx.Query("last_event_id")); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			fieldErrors["last_event_id"] = "must be an event ID"
+		} else {
+			lastEventID = &id
+		}
+	}
+
+	if len(fieldErrors) != 0 {
+		return nil, nil, errors.ErrInvalidPersonsQuery(fieldErrors)
+	}
+
+	return personIDs, lastEventID, nil
+}
+
+func writeFeedEvent(w *bufio.Writer, event feedEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.id, event.name, event.data)
+	return err
+}
+
+// GetPersonEvents streams the person events as server-sent events, the ID of the event is its position
+// in the outbox. The events missed since the Last-Event-ID are read from the outbox and sent first,
+// the reset event is sent instead if they are removed or there are too many of them,
+// so the client must reload the persons.
+func (d *eventsDelivery) GetPersonEvents(ctx *fiber.Ctx) error {
+	personIDs, lastEventID, err := parsePersonEventsQuery(ctx)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	subscriber, replay, replayed, err := d.feed.subscribe(ctx.UserContext(), personIDs, lastEventID)
+	if stderrors.Is(err, errEventFeedClosed) {
+		return problem.Send(ctx, problem.New(fiber.StatusServiceUnavailable, "server is shutting down"))
+	}
+
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, mimeEventStream)
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set("X-Accel-Buffering", "no") // disables the buffering of nginx
+	bodystream.SetWriter(ctx, func(w *bufio.Writer) {
+		defer d.feed.unsubscribe(subscriber)
+
+		err := d.streamEvents(w, subscriber, replay, replayed)
+		if err != nil {
+			d.logger.Debug("person events stream closed", slog.String("error", err.Error()))
+		}
+	})
+
+	return nil
+}
+
+func (d *eventsDelivery) streamEvents(
+	w *bufio.Writer,
+	subscriber *feedSubscriber,
+	replay []feedEvent,
+	replayed bool,
+) error {
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry)
+	if err != nil {
+		return err
+	}
+
+	if !replayed {
+		_, err = fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, event := range replay {
+		err = writeFeedEvent(w, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(d.feed.config.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-d.feed.done:
+			return errEventFeedClosed
+		case event, ok := <-subscriber.events:
+			if !ok {
+				return stderrors.New("client is too slow")
+			}
+
+			err = writeFeedEvent(w, event)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if err == nil {
+			err = w.Flush()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
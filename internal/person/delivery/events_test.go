@@ -0,0 +1,330 @@
+package delivery_test
+
+import (
+	"bufio"
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryOutbox is the outbox shared by the instances of the feed.
+type memoryOutbox struct {
+	mu     sync.Mutex
+	events []models.OutboxEvent
+	first  int64
+}
+
+func (o *memoryOutbox) append(eventType models.PersonEventType, personID int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events = append(o.events, models.OutboxEvent{
+		Sequence: int64(len(o.events) + 1),
+		PersonEvent: models.PersonEvent{
+			ID:         "event",
+			Type:       eventType,
+			Person:     models.Person{ID: personID, PersonProperties: models.PersonProperties{Name: "Aboba"}},
+			OccurredAt: time.Now(),
+		},
+	})
+}
+
+// removeBefore removes the events before the sequence like the cleanup of the outbox.
+func (o *memoryOutbox) removeBefore(sequence int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.first = sequence
+}
+
+func (o *memoryOutbox) GetOutboxEvents(_ context.Context, afterSequence int64, limit int) ([]models.OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var events []models.OutboxEvent
+
+	for _, event := range o.events {
+		if event.Sequence > afterSequence && event.Sequence >= o.first && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (o *memoryOutbox) GetOutboxBounds(context.Context) (first, last int64, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return max(o.first, 1), int64(len(o.events)), nil
+}
+
+type EventFeedSuite struct {
+	suite.Suite
+}
+
+// newFeed returns the running feed of the outbox.
+func (*EventFeedSuite) newFeed(t provider.T, outbox *memoryOutbox, replayLimit int) *delivery.EventFeed {
+	config := delivery.EventsConfig{PollInterval: 10 * time.Millisecond, ReplayLimit: replayLimit}
+	feed, err := delivery.NewEventFeed(context.Background(), config, outbox, slog.Default())
+	t.Require().NoError(err)
+
+	go feed.Run(context.Background())
+
+	return feed
+}
+
+// sseEvent is the event received by the client, the comments are skipped.
+type sseEvent struct {
+	id   string
+	name string
+	data string
+}
+
+// startFeed serves the change feed on the random port and returns its URL.
+func (*EventFeedSuite) startFeed(t provider.T, feed *delivery.EventFeed) string {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(bodystream.Middleware)
+	delivery.AddEventHandlers(app, feed, logger)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	t.Require().NoError(err)
+
+	go func() {
+		_ = app.Listener(listener)
+	}()
+
+	t.Cleanup(func() {
+		feed.Close()
+		_ = app.Shutdown()
+	})
+
+	return "http://" + listener.Addr().String() + "/persons/events"
+}
+
+// connect returns the channel of the events of the stream, it is closed when the stream ends.
+func (*EventFeedSuite) connect(t provider.T, url, lastEventID string) <-chan sseEvent {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	t.Require().NoError(err)
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	t.Require().NoError(err)
+	t.Require().Equal(http.StatusOK, resp.StatusCode)
+	t.Require().Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan sseEvent, 16)
+
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		var event sseEvent
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			field, value, _ := strings.Cut(line, ": ")
+
+			switch field {
+			case "id":
+				event.id = value
+			case "event":
+				event.name = value
+			case "data":
+				event.data = value
+			case "":
+				if event.name != "" {
+					events <- event
+				}
+
+				event = sseEvent{}
+			}
+		}
+	}()
+
+	return events
+}
+
+func (*EventFeedSuite) receive(t provider.T, events <-chan sseEvent) sseEvent {
+	select {
+	case event, ok := <-events:
+		t.Require().True(ok, "stream is closed")
+		return event
+	case <-time.After(5 * time.Second):
+		t.Errorf("event is not received")
+		t.FailNow()
+
+		return sseEvent{}
+	}
+}
+
+// waitSubscribed waits until the live stream is subscribed by storing the event.
+func (s *EventFeedSuite) waitSubscribed(t provider.T, outbox *memoryOutbox, events <-chan sseEvent) sseEvent {
+	outbox.append(models.PersonCreated, 1)
+	return s.receive(t, events)
+}
+
+func (s *EventFeedSuite) TestStreamAndReplay(t provider.T) {
+	t.Epic("Change feed")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	outbox := &memoryOutbox{}
+	url := s.startFeed(t, s.newFeed(t, outbox, 0))
+	live := s.connect(t, url, "")
+	first := s.waitSubscribed(t, outbox, live)
+	// act
+	outbox.append(models.PersonUpdated, 2)
+	outbox.append(models.PersonDeleted, 1)
+	updated := s.receive(t, live)
+	deleted := s.receive(t, live)
+	replayed := s.connect(t, url+"?person_id=1", first.id)
+	// assert
+	t.Require().Equal("1", first.id)
+	t.Require().Equal("person.created", first.name)
+	t.Require().Contains(first.data, `"person_id":1`)
+	t.Require().Equal("2", updated.id)
+	t.Require().Equal("person.updated", updated.name)
+	t.Require().Equal("3", deleted.id)
+	t.Require().Equal("person.deleted", deleted.name)
+	t.Require().NotContains(deleted.data, `"person":`)
+	t.Require().Equal(deleted, s.receive(t, replayed))
+}
+
+func (s *EventFeedSuite) TestReplayAfterRestart(t provider.T) {
+	t.Epic("Change feed")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	outbox := &memoryOutbox{}
+	for personID := 1; personID <= 3; personID++ {
+		outbox.append(models.PersonCreated, personID)
+	}
+	url := s.startFeed(t, s.newFeed(t, outbox, 0))
+	// act
+	reconnected := s.connect(t, url, "1")
+	second := s.receive(t, reconnected)
+	third := s.receive(t, reconnected)
+	outbox.append(models.PersonUpdated, 1)
+	live := s.receive(t, reconnected)
+	// assert
+	t.Require().Equal("2", second.id)
+	t.Require().Equal("3", third.id)
+	t.Require().Equal("4", live.id)
+	t.Require().Equal("person.updated", live.name)
+}
+
+func (s *EventFeedSuite) TestEventsOfAnotherInstance(t provider.T) {
+	t.Epic("Change feed")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	outbox := &memoryOutbox{}
+	first := s.startFeed(t, s.newFeed(t, outbox, 0))
+	second := s.startFeed(t, s.newFeed(t, outbox, 0))
+	live := s.connect(t, second, "")
+	received := s.waitSubscribed(t, outbox, live)
+	// act
+	outbox.append(models.PersonDeleted, 1)
+	deleted := s.receive(t, live)
+	reconnected := s.connect(t, first, received.id)
+	// assert
+	t.Require().Equal(deleted, s.receive(t, reconnected))
+}
+
+func (s *EventFeedSuite) TestResetAfterRemovedEvents(t provider.T) {
+	t.Epic("Change feed")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	outbox := &memoryOutbox{}
+	url := s.startFeed(t, s.newFeed(t, outbox, 0))
+	live := s.connect(t, url, "")
+	first := s.waitSubscribed(t, outbox, live)
+	for personID := 2; personID <= 4; personID++ {
+		outbox.append(models.PersonCreated, personID)
+		s.receive(t, live)
+	}
+	// act
+	outbox.removeBefore(3)
+	reconnected := s.connect(t, url, first.id)
+	// assert
+	t.Require().Equal("reset", s.receive(t, reconnected).name)
+}
+
+func (s *EventFeedSuite) TestResetAfterTooManyEvents(t provider.T) {
+	t.Epic("Change feed")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	outbox := &memoryOutbox{}
+	url := s.startFeed(t, s.newFeed(t, outbox, 2))
+	live := s.connect(t, url, "")
+	first := s.waitSubscribed(t, outbox, live)
+	for personID := 2; personID <= 4; personID++ {
+		outbox.append(models.PersonCreated, personID)
+		s.receive(t, live)
+	}
+	// act
+	reconnected := s.connect(t, url, first.id)
+	// assert
+	t.Require().Equal("reset", s.receive(t, reconnected).name)
+	outbox.append(models.PersonUpdated, 1)
+	t.Require().Equal("5", s.receive(t, reconnected).id)
+}
+
+func (s *EventFeedSuite) TestCloseEndsStreams(t provider.T) {
+	t.Epic("Change feed")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	outbox := &memoryOutbox{}
+	feed := s.newFeed(t, outbox, 0)
+	url := s.startFeed(t, feed)
+	live := s.connect(t, url, "")
+	s.waitSubscribed(t, outbox, live)
+	// act
+	feed.Close()
+	// assert
+	select {
+	case _, ok := <-live:
+		t.Require().False(ok)
+	case <-time.After(5 * time.Second):
+		t.Errorf("stream is not closed")
+		t.FailNow()
+	}
+	resp, err := http.Get(url)
+	t.Require().NoError(err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	t.Require().Equal(http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestEventFeed(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(EventFeedSuite))
+}
@@ -0,0 +1,44 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"log/slog"
+)
+
+// resolverError is the error of the resolver. The problem of the REST API is sent in the extensions of the error,
+// so clients of both APIs handle the errors the same way.
+type resolverError struct {
+	details problem.Details
+}
+
+func (e resolverError) Error() string {
+	return e.details.Detail
+}
+
+func (e resolverError) Extensions() map[string]any {
+	return e.details.Map()
+}
+
+func (r *resolver) resolverError(err error) error {
+	details, ok := errors.FromError(err)
+	if !ok {
+		r.logger.Error(err.Error())
+		return resolverError{details: problem.New(500, "internal server error")}
+	}
+
+	r.logger.Warn(err.Error())
+
+	return resolverError{details: details}
+}
+
+// panicLogger logs the panics of the resolvers, which are returned to the client as errors.
+type panicLogger struct {
+	logger *slog.Logger
+}
+
+func (l panicLogger) LogPanic(ctx context.Context, value any) {
+	l.logger.ErrorContext(ctx, fmt.Sprintf("panic in GraphQL resolver: %v", value))
+}
@@ -0,0 +1,77 @@
+// Package gql implements the GraphQL API of the persons, which resolves through the same use cases as the REST API.
+package gql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/graph-gophers/graphql-go"
+	"log/slog"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxQueryDepth limits the nesting of the queries, the schema has no recursive types, so the real queries are shallow.
+const maxQueryDepth = 10
+
+type Config struct {
+	// Playground enables the GraphiQL page on GET requests of the endpoint.
+	Playground bool `koanf:"playground"`
+}
+
+type handler struct {
+	schema *graphql.Schema
+	logger *slog.Logger
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func AddHandlers(root fiber.Router, config Config, useCase delivery.UseCase, logger *slog.Logger) {
+	handler := &handler{
+		schema: graphql.MustParseSchema(
+			schemaSDL,
+			&resolver{useCase: useCase, logger: logger},
+			graphql.MaxDepth(maxQueryDepth),
+			graphql.Logger(panicLogger{logger: logger}),
+		),
+		logger: logger,
+	}
+
+	root.Post("/graphql", handler.Execute)
+
+	if config.Playground {
+		root.Get("/graphql", sendPlayground)
+	}
+}
+
+// Execute executes the GraphQL request. The errors of the resolvers are sent in the response with the 200 status,
+// the request itself is rejected only if it cannot be parsed.
+func (h *handler) Execute(ctx *fiber.Ctx) error {
+	var req request
+
+	err := json.Unmarshal(ctx.Body(), &req)
+	if err != nil {
+		return h.sendValidationError(ctx, errors.ErrInvalidGraphQLRequest(err.Error()))
+	}
+
+	if req.Query == "" {
+		return h.sendValidationError(ctx, errors.ErrInvalidGraphQLRequest("query is required"))
+	}
+
+	response := h.schema.Exec(ctx.UserContext(), req.Query, req.OperationName, req.Variables)
+
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (h *handler) sendValidationError(ctx *fiber.Ctx, err errors.ValidationError) error {
+	h.logger.Warn(err.Error())
+	return problem.Send(ctx, err.Problem())
+}
@@ -0,0 +1,309 @@
+package gql_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/gql"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type GraphQLSuite struct {
+	suite.Suite
+}
+
+// age returns the pointer to the age of the person.
+func age(value int) *int {
+	return &value
+}
+
+func (*GraphQLSuite) newApp(config gql.Config, useCase *UseCaseMock) *fiber.App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New()
+	gql.AddHandlers(app, config, useCase, logger)
+
+	return app
+}
+
+// execute posts the GraphQL request to the app and returns the response with its decoded JSON body.
+func (*GraphQLSuite) execute(t provider.T, app *fiber.App, body string) (*http.Response, map[string]any) {
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	res, err := app.Test(req, -1)
+	t.Require().NoError(err)
+
+	defer res.Body.Close()
+
+	var resBody map[string]any
+	t.Require().NoError(json.NewDecoder(res.Body).Decode(&resBody))
+
+	return res, resBody
+}
+
+// query builds the body of the GraphQL request.
+func query(t provider.T, query string, variables map[string]any) string {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	t.Require().NoError(err)
+
+	return string(body)
+}
+
+// firstError returns the first error of the GraphQL response.
+func firstError(t provider.T, body map[string]any) map[string]any {
+	errs, ok := body["errors"].([]any)
+	t.Require().True(ok, "response has no errors: %v", body)
+	t.Require().NotEmpty(errs)
+
+	return errs[0].(map[string]any)
+}
+
+func (s *GraphQLSuite) TestPersonVersionIsNotTruncated(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", 1).Return(models.Person{
+		ID: 1, PersonProperties: models.PersonProperties{Name: "Alice", Age: age(30)}, Version: 1 << 32,
+	}, nil)
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	res, body := s.execute(t, app, query(t, `{ person(id: "1") { id name age version } }`, nil))
+	// assert
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().NotContains(body, "errors")
+	t.Require().Equal(map[string]any{
+		"person": map[string]any{"id": "1", "name": "Alice", "age": float64(30), "version": "4294967296"},
+	}, body["data"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *GraphQLSuite) TestPersonNotFound(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", 1).Return(models.Person{}, usecase.NotFound(models.ErrPersonNotFound))
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	_, body := s.execute(t, app, query(t, `{ person(id: "1") { id } }`, nil))
+	// assert
+	t.Require().NotContains(body, "errors")
+	t.Require().Equal(map[string]any{"person": nil}, body["data"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *GraphQLSuite) TestInvalidPersonID(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	_, body := s.execute(t, app, query(t, `{ person(id: "x") { id } }`, nil))
+	// assert
+	extensions := firstError(t, body)["extensions"].(map[string]any)
+	t.Require().Equal(float64(http.StatusBadRequest), extensions["status"])
+	useCase.AssertNotCalled(t, "GetPerson", mock.Anything)
+}
+
+func (s *GraphQLSuite) TestUpdatePersonExpectedVersion(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.CRITICAL)
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+	}{
+		{
+			name:  "string literal",
+			query: `mutation { updatePerson(id: "1", person: {age: 31}, expectedVersion: "4294967297") { version } }`,
+		},
+		{
+			name:      "string variable",
+			query:     `mutation($v: Version) { updatePerson(id: "1", person: {age: 31}, expectedVersion: $v) { version } }`,
+			variables: map[string]any{"v": "4294967297"},
+		},
+		{
+			name:      "number variable",
+			query:     `mutation($v: Version) { updatePerson(id: "1", person: {age: 31}, expectedVersion: $v) { version } }`,
+			variables: map[string]any{"v": 4294967297},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			useCase.On("UpdatePerson", models.Person{ID: 1, PersonProperties: models.PersonProperties{Age: age(31)}}, int64(4294967297)).
+				Return(models.Person{ID: 1, PersonProperties: models.PersonProperties{Age: age(31)}, Version: 4294967298}, nil)
+			app := s.newApp(gql.Config{}, useCase)
+			// act
+			_, body := s.execute(t, app, query(t, test.query, test.variables))
+			// assert
+			t.Require().NotContains(body, "errors")
+			t.Require().Equal(map[string]any{"updatePerson": map[string]any{"version": "4294967298"}}, body["data"])
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func (s *GraphQLSuite) TestInvalidExpectedVersion(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	_, body := s.execute(t, app, query(t, `mutation { deletePerson(id: "1", expectedVersion: "v1") }`, nil))
+	// assert
+	t.Require().NotEmpty(firstError(t, body)["message"])
+	useCase.AssertNotCalled(t, "DeletePerson", mock.Anything, mock.Anything)
+}
+
+func (s *GraphQLSuite) TestDeletePersonVersionMismatch(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("DeletePerson", 1, int64(2)).Return(models.ErrVersionMismatch)
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	_, body := s.execute(t, app, query(t, `mutation { deletePerson(id: "1", expectedVersion: 2) }`, nil))
+	// assert
+	extensions := firstError(t, body)["extensions"].(map[string]any)
+	t.Require().Equal("urn:problem-type:persons:precondition-failed", extensions["type"])
+	t.Require().Equal(float64(http.StatusPreconditionFailed), extensions["status"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *GraphQLSuite) TestInvalidPerson(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	_, body := s.execute(t, app, query(t, `mutation { createPerson(person: {name: "", age: -1}) { id } }`, nil))
+	// assert
+	extensions := firstError(t, body)["extensions"].(map[string]any)
+	t.Require().Equal("urn:problem-type:persons:invalid-request", extensions["type"])
+	t.Require().Contains(extensions["errors"], "name")
+	t.Require().Contains(extensions["errors"], "age")
+	useCase.AssertNotCalled(t, "CreatePerson", mock.Anything)
+}
+
+func (s *GraphQLSuite) TestInvalidPersonsPage(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	_, body := s.execute(t, app, query(t, `{ persons(page: {offset: -1, limit: 1001}) { total } }`, nil))
+	// assert
+	extensions := firstError(t, body)["extensions"].(map[string]any)
+	t.Require().Equal(map[string]any{
+		"page.offset": "must be a non-negative integer",
+		"page.limit":  "must be between 0 and 1000",
+	}, extensions["errors"])
+	useCase.AssertNotCalled(t, "GetPersons", mock.Anything)
+}
+
+func (s *GraphQLSuite) TestInternalErrorIsSanitized(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPersons", mock.Anything).Return(models.PersonsPage{}, errors.New("connection refused"))
+	app := s.newApp(gql.Config{}, useCase)
+	// act
+	_, body := s.execute(t, app, query(t, `{ persons { total } }`, nil))
+	// assert
+	t.Require().Equal("internal server error", firstError(t, body)["message"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *GraphQLSuite) TestInvalidRequest(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.NORMAL)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed JSON", body: `{"query":`},
+		{name: "no query", body: `{"variables": {}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t provider.T) {
+			// arrange
+			app := s.newApp(gql.Config{}, new(UseCaseMock))
+			// act
+			res, body := s.execute(t, app, test.body)
+			// assert
+			t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+			t.Require().Equal(problem.MIMEProblemJSON, res.Header.Get(fiber.HeaderContentType))
+			t.Require().Equal("urn:problem-type:persons:invalid-request", body["type"])
+		})
+	}
+}
+
+func (s *GraphQLSuite) TestPlayground(t provider.T) {
+	t.Epic("GraphQL")
+	t.Severity(allure.MINOR)
+
+	tests := []struct {
+		name       string
+		playground bool
+		status     int
+	}{
+		{name: "disabled", playground: false, status: http.StatusMethodNotAllowed},
+		{name: "enabled", playground: true, status: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t provider.T) {
+			// arrange
+			app := s.newApp(gql.Config{Playground: test.playground}, new(UseCaseMock))
+			req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+			// act
+			res, err := app.Test(req, -1)
+			// assert
+			t.Require().NoError(err)
+			t.Require().Equal(test.status, res.StatusCode)
+		})
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(GraphQLSuite))
+}
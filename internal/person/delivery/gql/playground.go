@@ -0,0 +1,28 @@
+package gql
+
+import "github.com/gofiber/fiber/v2"
+
+// playgroundPage is the GraphiQL page loaded from the CDN, it sends the queries to the URL of the page.
+const playgroundPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Persons GraphiQL</title>
+	<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+</head>
+<body style="margin: 0;">
+	<div id="graphiql" style="height: 100vh;"></div>
+	<script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+	<script>
+		const fetcher = GraphiQL.createFetcher({ url: window.location.pathname });
+		ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, { fetcher }));
+	</script>
+</body>
+</html>`
+
+func sendPlayground(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.Status(fiber.StatusOK).SendString(playgroundPage)
+}
@@ -0,0 +1,324 @@
+package gql
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/graph-gophers/graphql-go"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+const maxPageLimit = 1000
+
+type resolver struct {
+	useCase delivery.UseCase
+	logger  *slog.Logger
+}
+
+type personResolver struct {
+	person models.Person
+}
+
+func (r personResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(r.person.ID))
+}
+
+func (r personResolver) Name() string {
+	return r.person.Name
+}
+
+func (r personResolver) Age() *int32 {
+	if r.person.Age == nil {
+		return nil
+	}
+
+	age := int32(*r.person.Age)
+
+	return &age
+}
+
+func (r personResolver) Address() string {
+	return r.person.Address
+}
+
+func (r personResolver) Work() string {
+	return r.person.Work
+}
+
+func (r personResolver) Version() version {
+	return version(r.person.Version)
+}
+
+func (r personResolver) DeletedAt() *graphql.Time {
+	if r.person.DeletedAt == nil {
+		return nil
+	}
+
+	return &graphql.Time{Time: *r.person.DeletedAt}
+}
+
+type personsPageResolver struct {
+	page models.PersonsPage
+}
+
+func (r personsPageResolver) Items() []personResolver {
+	items := make([]personResolver, 0, len(r.page.Persons))
+	for _, person := range r.page.Persons {
+		items = append(items, personResolver{person: person})
+	}
+
+	return items
+}
+
+func (r personsPageResolver) Total() int32 {
+	return int32(r.page.Total)
+}
+
+func (r personsPageResolver) HasMore() bool {
+	return r.page.HasMore
+}
+
+func parsePersonID(id graphql.ID) (int, error) {
+	personID, err := strconv.Atoi(string(id))
+	if err != nil || personID <= 0 {
+		return 0, errors.ErrInvalidID
+	}
+
+	return personID, nil
+}
+
+func (r *resolver) Person(ctx context.Context, args struct{ ID graphql.ID }) (*personResolver, error) {
+	personID, err := parsePersonID(args.ID)
+	if err != nil {
+		return nil, r.resolverError(err)
+	}
+
+	person, err := r.useCase.GetPerson(ctx, personID)
+	if usecase.KindOf(err) == usecase.KindNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, r.resolverError(err)
+	}
+
+	return &personResolver{person: person}, nil
+}
+
+type personsFilterInput struct {
+	NameContains    *string
+	NamePrefix      *string
+	Work            *string
+	AgeMin          *int32
+	AgeMax          *int32
+	AddressContains *string
+	Deleted         string
+}
+
+type sortKeyInput struct {
+	Field      string
+	Descending bool
+}
+
+type pageInput struct {
+	Offset int32
+	Limit  int32
+}
+
+type personsArgs struct {
+	Filter *personsFilterInput
+	Sort   *[]sortKeyInput
+	Page   *pageInput
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+func optionalInt(value *int32) *int {
+	if value == nil {
+		return nil
+	}
+
+	res := int(*value)
+
+	return &res
+}
+
+func newPersonsFilter(input *personsFilterInput) models.PersonsFilter {
+	if input == nil {
+		return models.PersonsFilter{}
+	}
+
+	filter := models.PersonsFilter{
+		NameContains:    optionalString(input.NameContains),
+		NamePrefix:      optionalString(input.NamePrefix),
+		Work:            optionalString(input.Work),
+		AgeMin:          optionalInt(input.AgeMin),
+		AgeMax:          optionalInt(input.AgeMax),
+		AddressContains: optionalString(input.AddressContains),
+	}
+
+	if input.Deleted != "EXCLUDE" {
+		filter.Deleted = models.DeletedFilter(strings.ToLower(input.Deleted))
+	}
+
+	return filter
+}
+
+func newPersonsQuery(args personsArgs) (models.PersonsQuery, error) {
+	query := models.PersonsQuery{
+		Filter:    newPersonsFilter(args.Filter),
+		Limit:     100,
+		WithTotal: true,
+	}
+
+	if args.Sort != nil {
+		for _, key := range *args.Sort {
+			query.Sort = append(query.Sort, models.SortKey{
+				Field:      models.PersonField(strings.ToLower(key.Field)),
+				Descending: key.Descending,
+			})
+		}
+	}
+
+	if args.Page != nil {
+		query.Offset, query.Limit = int64(args.Page.Offset), int64(args.Page.Limit)
+	}
+
+	fieldErrors := make(map[string]string)
+
+	if query.Offset < 0 {
+		fieldErrors["page.offset"] = "must be a non-negative integer"
+	}
+
+	if query.Limit < 0 || query.Limit > maxPageLimit {
+		fieldErrors["page.limit"] = "must be between 0 and " + strconv.Itoa(maxPageLimit)
+	}
+
+	if query.Filter.AgeMin != nil && query.Filter.AgeMax != nil && *query.Filter.AgeMin > *query.Filter.AgeMax {
+		fieldErrors["filter.ageMin"] = "must not be greater than ageMax"
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.PersonsQuery{}, errors.ErrInvalidPersonsQuery(fieldErrors)
+	}
+
+	return query, nil
+}
+
+func (r *resolver) Persons(ctx context.Context, args personsArgs) (personsPageResolver, error) {
+	query, err := newPersonsQuery(args)
+	if err != nil {
+		return personsPageResolver{}, r.resolverError(err)
+	}
+
+	page, err := r.useCase.GetPersons(ctx, query)
+	if err != nil {
+		return personsPageResolver{}, r.resolverError(err)
+	}
+
+	return personsPageResolver{page: page}, nil
+}
+
+type personInput struct {
+	Name    string
+	Age     *int32
+	Address string
+	Work    string
+}
+
+func (r *resolver) CreatePerson(ctx context.Context, args struct{ Person personInput }) (personResolver, error) {
+	properties := delivery.PersonProperties{
+		Name:    args.Person.Name,
+		Age:     optionalInt(args.Person.Age),
+		Address: args.Person.Address,
+		Work:    args.Person.Work,
+	}
+
+	err := properties.Validate(false)
+	if err != nil {
+		return personResolver{}, r.resolverError(err)
+	}
+
+	person, err := r.useCase.CreatePerson(ctx, properties.ToProperties())
+	if err != nil {
+		return personResolver{}, r.resolverError(err)
+	}
+
+	return personResolver{person: person}, nil
+}
+
+type personPatchInput struct {
+	Name    *string
+	Age     *int32
+	Address *string
+	Work    *string
+}
+
+type updatePersonArgs struct {
+	ID              graphql.ID
+	Person          personPatchInput
+	ExpectedVersion *version
+}
+
+func expectedVersion(expected *version) int64 {
+	if expected == nil {
+		return models.AnyVersion
+	}
+
+	return int64(*expected)
+}
+
+func (r *resolver) UpdatePerson(ctx context.Context, args updatePersonArgs) (personResolver, error) {
+	personID, err := parsePersonID(args.ID)
+	if err != nil {
+		return personResolver{}, r.resolverError(err)
+	}
+
+	properties := delivery.PersonProperties{
+		Name:    optionalString(args.Person.Name),
+		Age:     optionalInt(args.Person.Age),
+		Address: optionalString(args.Person.Address),
+		Work:    optionalString(args.Person.Work),
+	}
+
+	err = properties.Validate(true)
+	if err != nil {
+		return personResolver{}, r.resolverError(err)
+	}
+
+	person, err := r.useCase.UpdatePerson(ctx, properties.ToPerson(personID), expectedVersion(args.ExpectedVersion))
+	if err != nil {
+		return personResolver{}, r.resolverError(err)
+	}
+
+	return personResolver{person: person}, nil
+}
+
+type deletePersonArgs struct {
+	ID              graphql.ID
+	ExpectedVersion *version
+}
+
+func (r *resolver) DeletePerson(ctx context.Context, args deletePersonArgs) (graphql.ID, error) {
+	personID, err := parsePersonID(args.ID)
+	if err != nil {
+		return "", r.resolverError(err)
+	}
+
+	err = r.useCase.DeletePerson(ctx, personID, expectedVersion(args.ExpectedVersion))
+	if err != nil {
+		return "", r.resolverError(err)
+	}
+
+	return args.ID, nil
+}
@@ -0,0 +1,39 @@
+package gql_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/stretchr/testify/mock"
+)
+
+// UseCaseMock mocks the use cases called by the resolvers under test, the other use cases are not implemented.
+type UseCaseMock struct {
+	delivery.UseCase
+	mock.Mock
+}
+
+func (u *UseCaseMock) GetPersons(_ context.Context, query models.PersonsQuery) (models.PersonsPage, error) {
+	args := u.Called(query)
+	return args.Get(0).(models.PersonsPage), args.Error(1)
+}
+
+func (u *UseCaseMock) GetPerson(_ context.Context, personID int) (models.Person, error) {
+	args := u.Called(personID)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) CreatePerson(_ context.Context, person models.PersonProperties) (models.Person, error) {
+	args := u.Called(person)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) UpdatePerson(_ context.Context, person models.Person, version int64) (models.Person, error) {
+	args := u.Called(person, version)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) DeletePerson(_ context.Context, personID int, version int64) error {
+	args := u.Called(personID, version)
+	return args.Error(0)
+}
@@ -0,0 +1,44 @@
+package gql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// version is the Version scalar. The version of the person is 64-bit, so it is sent as the decimal string
+// instead of Int, which is 32-bit in GraphQL. Integer literals are accepted as input too.
+type version int64
+
+func (version) ImplementsGraphQLType(name string) bool {
+	return name == "Version"
+}
+
+func (v *version) UnmarshalGraphQL(input any) error {
+	switch input := input.(type) {
+	case string:
+		value, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			return fmt.Errorf("version %q is not a 64-bit integer", input)
+		}
+
+		*v = version(value)
+	case int32:
+		*v = version(input)
+	case float64:
+		if input != math.Trunc(input) || math.Abs(input) > 1<<53 {
+			return fmt.Errorf("version %v is not an exact integer, send it as a string", input)
+		}
+
+		*v = version(input)
+	default:
+		return fmt.Errorf("wrong type of version: %T", input)
+	}
+
+	return nil
+}
+
+func (v version) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(v), 10))
+}
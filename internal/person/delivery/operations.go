@@ -0,0 +1,279 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+)
+
+const batchVerb = ":batch"
+
+// batchOperation is the operation of the mixed batch. The target person of patch and delete operations
+// is set either by ID or by the index of the earlier create operation of the batch.
+type batchOperation struct {
+	Op      string          `json:"op"`
+	ID      int             `json:"id"`
+	Ref     *int            `json:"ref"`
+	IfMatch string          `json:"if_match"`
+	Person  json.RawMessage `json:"person"`
+	Patch   json.RawMessage `json:"patch"`
+}
+
+// addNestedErrors adds the validation errors of the nested object to the errors of the operation.
+func addNestedErrors(fieldErrors map[string]string, field string, err error) {
+	var validationErr errors.ValidationError
+	if !stderrors.As(err, &validationErr) {
+		return
+	}
+
+	if len(validationErr.Errors) == 0 {
+		fieldErrors[field] = validationErr.Message
+		return
+	}
+
+	for nested, msg := range validationErr.Errors {
+		if strings.HasPrefix(nested, "/") {
+			fieldErrors[field+nested] = msg // JSON patch operation
+		} else {
+			fieldErrors[field+"."+nested] = msg
+		}
+	}
+}
+
+// parseBatchPatch parses the JSON Patch if the value is an array and the JSON Merge Patch otherwise.
+func parseBatchPatch(value json.RawMessage) (models.PersonPatch, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+		return parseJSONPatch(value)
+	}
+
+	return parseMergePatch(value)
+}
+
+func parseBatchTarget(dto batchOperation, fieldErrors map[string]string) {
+	switch {
+	case dto.Ref != nil && dto.ID != 0:
+		fieldErrors["ref"] = "must not be set together with id"
+	case dto.Ref != nil && *dto.Ref < 0:
+		fieldErrors["ref"] = "must be a non-negative integer"
+	case dto.Ref == nil && dto.ID <= 0:
+		fieldErrors["id"] = "must be a positive integer"
+	}
+
+	if _, ok := parseExpectedVersion(dto.IfMatch); !ok {
+		fieldErrors["if_match"] = "must be a strong entity tag or *"
+	}
+
+	if dto.Person != nil {
+		fieldErrors["person"] = "is allowed only for create"
+	}
+}
+
+// parseBatchOperation parses the operation of the batch. The returned errors are nil if the operation is valid.
+func parseBatchOperation(data []byte) (models.BatchOperation, map[string]string) {
+	var dto batchOperation
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&dto)
+	if err != nil {
+		fieldErrors := newDecodeError(err).Errors
+		if len(fieldErrors) == 0 {
+			fieldErrors["operation"] = "must be a JSON object: " + err.Error()
+		}
+
+		return models.BatchOperation{}, fieldErrors
+	}
+
+	version, _ := parseExpectedVersion(dto.IfMatch)
+	operation := models.BatchOperation{
+		Type:     models.BatchOperationType(dto.Op),
+		PersonID: dto.ID,
+		Ref:      dto.Ref,
+		Version:  version,
+	}
+	fieldErrors := make(map[string]string)
+
+	switch operation.Type {
+	case models.BatchCreate:
+		for field, set := range map[string]bool{
+			"id":       dto.ID != 0,
+			"ref":      dto.Ref != nil,
+			"if_match": dto.IfMatch != "",
+			"patch":    dto.Patch != nil,
+		} {
+			if set {
+				fieldErrors[field] = "is not allowed for create"
+			}
+		}
+
+		if dto.Person == nil {
+			fieldErrors["person"] = errors.FieldRequired
+			break
+		}
+
+		person, err := parsePersonProperties(dto.Person, false)
+		addNestedErrors(fieldErrors, "person", err)
+		operation.Person = person.ToProperties()
+	case models.BatchPatch:
+		parseBatchTarget(dto, fieldErrors)
+
+		if dto.Patch == nil {
+			fieldErrors["patch"] = errors.FieldRequired
+			break
+		}
+
+		operation.Patch, err = parseBatchPatch(dto.Patch)
+		addNestedErrors(fieldErrors, "patch", err)
+	case models.BatchDelete:
+		parseBatchTarget(dto, fieldErrors)
+
+		if dto.Patch != nil {
+			fieldErrors["patch"] = "is allowed only for patch"
+		}
+	default:
+		fieldErrors["op"] = "must be one of create, patch, delete"
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.BatchOperation{Type: operation.Type}, fieldErrors
+	}
+
+	return operation, nil
+}
+
+// newFailedBatchResult returns the result of the operation failed with the error. The status is the same
+// as for the single request of the operation. The returned flag is false for internal errors.
+func newFailedBatchResult(index int, operation models.BatchOperation, err error) (BatchOperationResult, bool) {
+	details, ok := errors.FromError(err)
+	if !ok {
+		return BatchOperationResult{}, false
+	}
+
+	result := BatchOperationResult{
+		Index:   index,
+		Op:      string(operation.Type),
+		Status:  details.Status,
+		Message: details.Detail,
+	}
+	result.Errors, _ = details.Extensions["errors"].(map[string]string)
+
+	return result, true
+}
+
+// newNotAppliedBatchReport returns the report of the rolled back batch.
+func newNotAppliedBatchReport(operations []models.BatchOperation, reason string) BatchReport {
+	report := BatchReport{
+		Results: make([]BatchOperationResult, len(operations)),
+	}
+
+	for i, operation := range operations {
+		report.Results[i] = BatchOperationResult{
+			Index:   i,
+			Op:      string(operation.Type),
+			Status:  fiber.StatusFailedDependency,
+			Message: "not applied because " + reason,
+		}
+	}
+
+	return report
+}
+
+func newAppliedBatchResult(
+	index int,
+	operation models.BatchOperation,
+	person models.Person,
+	personsPath string,
+) BatchOperationResult {
+	result := BatchOperationResult{
+		Index: index,
+		Op:    string(operation.Type),
+		ID:    person.ID,
+	}
+
+	switch operation.Type {
+	case models.BatchCreate:
+		result.Status = fiber.StatusCreated
+		result.Location = personsPath + "/" + strconv.Itoa(person.ID)
+	case models.BatchPatch:
+		result.Status = fiber.StatusOK
+	case models.BatchDelete:
+		result.Status = fiber.StatusNoContent
+		return result
+	}
+
+	dto := NewPersonDTO(person)
+	result.Person = &dto
+	result.ETag = formatETag(person.Version)
+
+	return result
+}
+
+// ApplyBatch applies create, patch and delete operations in one transaction and reports the status
+// of every operation. If any operation fails, nothing is applied and the response has its status.
+func (d *delivery) ApplyBatch(ctx *fiber.Ctx) error {
+	elements, err := splitBatch(ctx.Body(), d.config.maxBatchSize())
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	operations := make([]models.BatchOperation, len(elements))
+	invalid := make(map[int]map[string]string)
+
+	for i, element := range elements {
+		var fieldErrors map[string]string
+
+		operations[i], fieldErrors = parseBatchOperation(element)
+		if fieldErrors != nil {
+			invalid[i] = fieldErrors
+		}
+	}
+
+	if len(invalid) != 0 {
+		report := newNotAppliedBatchReport(operations, "of invalid operations")
+
+		for i, fieldErrors := range invalid {
+			report.Results[i].Status = fiber.StatusBadRequest
+			report.Results[i].Message = errors.ErrInvalidBatchOperation.Error()
+			report.Results[i].Errors = fieldErrors
+		}
+
+		return ctx.Status(fiber.StatusBadRequest).JSON(report)
+	}
+
+	persons, err := d.useCase.ApplyBatch(ctx.UserContext(), operations)
+
+	var batchErr models.BatchOperationError
+	if stderrors.As(err, &batchErr) {
+		result, ok := newFailedBatchResult(batchErr.Index, operations[batchErr.Index], batchErr.Err)
+		if !ok {
+			return err
+		}
+
+		report := newNotAppliedBatchReport(operations, "operation "+strconv.Itoa(batchErr.Index)+" failed")
+		report.Results[batchErr.Index] = result
+
+		return ctx.Status(result.Status).JSON(report)
+	}
+
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	report := BatchReport{
+		Committed: true,
+		Results:   make([]BatchOperationResult, len(operations)),
+	}
+	personsPath := strings.TrimSuffix(ctx.Path(), batchVerb)
+
+	for i, person := range persons {
+		report.Results[i] = newAppliedBatchResult(i, operations[i], person, personsPath)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(report)
+}
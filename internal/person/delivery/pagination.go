@@ -0,0 +1,99 @@
+package delivery
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// pageMediaType requests the persons list wrapped into the pagination envelope
+// instead of the bare array. The same can be done with the "envelope=true" query parameter.
+const pageMediaType = "application/vnd.persons.page+json"
+
+func envelopeRequested(ctx *fiber.Ctx) bool {
+	if ctx.QueryBool("envelope") {
+		return true
+	}
+
+	return ctx.Accepts(fiber.MIMEApplicationJSON, pageMediaType) == pageMediaType
+}
+
+func pageLink(ctx *fiber.Ctx, rel string, set map[string]string) string {
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+
+	ctx.Request().URI().QueryArgs().CopyTo(args)
+
+	for key, value := range set {
+		args.Set(key, value)
+	}
+
+	return "<" + ctx.Path() + "?" + string(args.QueryString()) + `>; rel="` + rel + `"`
+}
+
+func offsetLink(ctx *fiber.Ctx, rel string, offset int64) string {
+	return pageLink(ctx, rel, map[string]string{"offset": strconv.FormatInt(offset, 10)})
+}
+
+func cursorLink(ctx *fiber.Ctx, rel, cursor string) string {
+	return pageLink(ctx, rel, map[string]string{"cursor": cursor})
+}
+
+// setPageLinks sets the Link header (RFC 8288) with the first, previous, next and (if the total is known) last pages.
+func setPageLinks(ctx *fiber.Ctx, query models.PersonsQuery, page PersonsPage) {
+	if query.Limit == math.MaxInt64 || query.Limit <= 0 {
+		return
+	}
+
+	var links []string
+
+	if query.Keyset {
+		links = append(links, cursorLink(ctx, "first", ""))
+
+		if page.PrevCursor != "" {
+			links = append(links, cursorLink(ctx, "prev", page.PrevCursor))
+		}
+
+		if page.NextCursor != "" {
+			links = append(links, cursorLink(ctx, "next", page.NextCursor))
+		}
+	} else {
+		links = append(links, offsetLink(ctx, "first", 0))
+
+		if query.Offset > 0 {
+			links = append(links, offsetLink(ctx, "prev", max(query.Offset-query.Limit, 0)))
+		}
+
+		if page.HasMore {
+			links = append(links, offsetLink(ctx, "next", query.Offset+query.Limit))
+		}
+
+		if page.Total != nil && *page.Total > 0 {
+			links = append(links, offsetLink(ctx, "last", (*page.Total-1)/query.Limit*query.Limit))
+		}
+	}
+
+	ctx.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
+
+// setOffsetPageLinks sets the Link header with the first, previous and next pages of the list without the total.
+func setOffsetPageLinks(ctx *fiber.Ctx, offset, limit int64, hasMore bool) {
+	if limit <= 0 {
+		return
+	}
+
+	links := []string{offsetLink(ctx, "first", 0)}
+
+	if offset > 0 {
+		links = append(links, offsetLink(ctx, "prev", max(offset-limit, 0)))
+	}
+
+	if hasMore {
+		links = append(links, offsetLink(ctx, "next", offset+limit))
+	}
+
+	ctx.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
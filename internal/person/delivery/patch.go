@@ -0,0 +1,235 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimeMergePatchJSON = "application/merge-patch+json"
+	mimeJSONPatchJSON  = "application/json-patch+json"
+)
+
+var patchableFields = map[string]models.PersonField{
+	"name":    models.PersonFieldName,
+	"age":     models.PersonFieldAge,
+	"address": models.PersonFieldAddress,
+	"work":    models.PersonFieldWork,
+}
+
+// nullableFields are the optional fields, which are cleared by null. Other fields cannot be null.
+var nullableFields = map[models.PersonField]bool{
+	models.PersonFieldAge:     true,
+	models.PersonFieldAddress: true,
+	models.PersonFieldWork:    true,
+}
+
+const errNotNullable = "must not be null"
+
+func isJSONNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
+
+// pointerField returns the field referenced by the JSON Pointer (RFC 6901), e.g. "/name".
+func pointerField(pointer string) (models.PersonField, bool) {
+	if !strings.HasPrefix(pointer, "/") {
+		return "", false
+	}
+
+	field, ok := patchableFields[pointer[1:]]
+
+	return field, ok
+}
+
+// setPersonField sets the JSON value to the person field. The null value clears the nullable field.
+func setPersonField(person *models.Person, field models.PersonField, value json.RawMessage) error {
+	var target any
+
+	switch field {
+	case models.PersonFieldName:
+		target = &person.Name
+	case models.PersonFieldAge:
+		// the age may be shared with the copies of the person, so it is decoded into the new value
+		person.Age = nil
+		target = &person.Age
+	case models.PersonFieldAddress:
+		target = &person.Address
+	case models.PersonFieldWork:
+		target = &person.Work
+	}
+
+	if isJSONNull(value) {
+		if !nullableFields[field] {
+			return stderrors.New(errNotNullable)
+		}
+
+		reflect.ValueOf(target).Elem().SetZero()
+
+		return nil
+	}
+
+	return json.Unmarshal(value, target)
+}
+
+// validatePatchedPerson checks the person changed by the patch. The patch is applied by the use case,
+// so the violations are returned as the domain error.
+func validatePatchedPerson(person models.Person) (models.Person, error) {
+	err := NewPersonDTO(person).PersonProperties.Validate(false)
+
+	var validationErr errors.ValidationError
+	if stderrors.As(err, &validationErr) {
+		return models.Person{}, usecase.Validation(models.FieldErrors(validationErr.Errors))
+	}
+
+	if err != nil {
+		return models.Person{}, err
+	}
+
+	return person, nil
+}
+
+// mergePatch is a JSON Merge Patch (RFC 7396) of the person.
+type mergePatch map[string]json.RawMessage
+
+func parseMergePatch(body []byte) (models.PersonPatch, error) {
+	var patch mergePatch
+
+	err := json.Unmarshal(body, &patch)
+	if err != nil {
+		return nil, errors.ErrInvalidPatch("merge patch must be a JSON object: "+err.Error(), nil)
+	}
+
+	fieldErrors := make(map[string]string)
+
+	for key, value := range patch {
+		field, ok := patchableFields[key]
+		if !ok {
+			fieldErrors[key] = errors.FieldUnknown
+		} else if isJSONNull(value) && !nullableFields[field] {
+			fieldErrors[key] = errNotNullable
+		}
+	}
+
+	if len(fieldErrors) != 0 {
+		return nil, errors.ErrInvalidPatch("merge patch contains invalid fields", fieldErrors)
+	}
+
+	return patch, nil
+}
+
+func (p mergePatch) Apply(person models.Person) (models.Person, error) {
+	fieldErrors := make(map[string]string)
+
+	for key, value := range p {
+		err := setPersonField(&person, patchableFields[key], value)
+		if err != nil {
+			fieldErrors[key] = "invalid value: " + err.Error()
+		}
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.Person{}, usecase.Validation(models.FieldErrors(fieldErrors))
+	}
+
+	return validatePatchedPerson(person)
+}
+
+// jsonPatchOperation is the operation of the JSON Patch. The path is the JSON Pointer of the field.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatch is a JSON Patch (RFC 6902) of the person. Only add, replace, remove and test operations are supported.
+type jsonPatch []jsonPatchOperation
+
+func parseJSONPatch(body []byte) (models.PersonPatch, error) {
+	var patch jsonPatch
+
+	err := json.Unmarshal(body, &patch)
+	if err != nil {
+		return nil, errors.ErrInvalidPatch("JSON patch must be an array of operations: "+err.Error(), nil)
+	}
+
+	fieldErrors := make(map[string]string)
+
+	for i, operation := range patch {
+		key := "/" + strconv.Itoa(i)
+
+		field, ok := pointerField(operation.Path)
+		if !ok {
+			fieldErrors[key+"/path"] = "unknown path " + strconv.Quote(operation.Path)
+		}
+
+		switch operation.Op {
+		case "add", "replace":
+			if operation.Value == nil {
+				fieldErrors[key+"/value"] = errors.FieldRequired
+			} else if ok && isJSONNull(operation.Value) && !nullableFields[field] {
+				fieldErrors[key+"/value"] = errNotNullable
+			}
+		case "test":
+			if operation.Value == nil {
+				fieldErrors[key+"/value"] = errors.FieldRequired
+			}
+		case "remove":
+			if ok && !nullableFields[field] {
+				fieldErrors[key+"/path"] = "required field " + strconv.Quote(operation.Path) + " cannot be removed"
+			}
+		default:
+			fieldErrors[key+"/op"] = "unsupported operation " + strconv.Quote(operation.Op)
+		}
+	}
+
+	if len(fieldErrors) != 0 {
+		return nil, errors.ErrInvalidPatch("invalid JSON patch operations", fieldErrors)
+	}
+
+	return patch, nil
+}
+
+func testPersonField(person models.Person, field models.PersonField, value json.RawMessage) bool {
+	expected := person
+
+	err := setPersonField(&expected, field, value)
+	if err != nil || isJSONNull(value) {
+		return false
+	}
+
+	return expected.FieldValue(field) == person.FieldValue(field)
+}
+
+func (p jsonPatch) Apply(person models.Person) (models.Person, error) {
+	for i, operation := range p {
+		field, _ := pointerField(operation.Path)
+
+		var err error
+
+		switch operation.Op {
+		case "test":
+			if !testPersonField(person, field, operation.Value) {
+				return models.Person{}, usecase.Conflict(models.ErrPatchTestFailed)
+			}
+		case "remove":
+			err = setPersonField(&person, field, json.RawMessage("null"))
+		default:
+			err = setPersonField(&person, field, operation.Value)
+		}
+
+		if err != nil {
+			return models.Person{}, usecase.Validation(models.FieldErrors{
+				"/" + strconv.Itoa(i) + "/value": "invalid value: " + err.Error(),
+			})
+		}
+	}
+
+	return validatePatchedPerson(person)
+}
@@ -0,0 +1,226 @@
+package delivery_test
+
+import (
+	"encoding/json"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type PatchSuite struct {
+	suite.Suite
+}
+
+func (*PatchSuite) newPerson() models.Person {
+	return models.Person{
+		ID:      1,
+		Version: 3,
+		PersonProperties: models.PersonProperties{
+			Name:    "Aboba",
+			Age:     age(42),
+			Address: "Address",
+			Work:    "Work",
+		},
+	}
+}
+
+// patch sends the patch of the person with the given content type and returns the response with its JSON body.
+func (*PatchSuite) patch(t provider.T, useCase *UseCaseMock, contentType, body string) (*http.Response, map[string]any) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New()
+	delivery.AddHandlers(app, delivery.Config{}, useCase, logger)
+
+	req := httptest.NewRequest(http.MethodPatch, "/persons/1", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, contentType)
+
+	res, err := app.Test(req, -1)
+	t.Require().NoError(err)
+
+	defer res.Body.Close()
+
+	var resBody map[string]any
+	t.Require().NoError(json.NewDecoder(res.Body).Decode(&resBody))
+
+	return res, resBody
+}
+
+func (s *PatchSuite) TestMergePatch(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", 1, models.AnyVersion).Return(s.newPerson(), nil)
+	// act
+	res, body := s.patch(t, useCase, "application/merge-patch+json", `{"age": 43, "work": null}`)
+	// assert
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().Equal("Aboba", body["name"])
+	t.Require().EqualValues(43, body["age"])
+	t.Require().Equal("Address", body["address"])
+	t.Require().Empty(body["work"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *PatchSuite) TestMergePatchNullRequiredField(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	// act
+	res, body := s.patch(t, useCase, "application/merge-patch+json", `{"name": null, "age": null}`)
+	// assert
+	t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	t.Require().Equal(map[string]any{"name": "must not be null"}, body["errors"])
+	useCase.AssertNotCalled(t, "PatchPerson", mock.Anything, mock.Anything)
+}
+
+func (s *PatchSuite) TestMergePatchNullAge(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", 1, models.AnyVersion).Return(s.newPerson(), nil)
+	// act
+	res, body := s.patch(t, useCase, "application/merge-patch+json", `{"age": null}`)
+	// assert
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().Contains(body, "age")
+	t.Require().Nil(body["age"])
+	t.Require().Equal("Aboba", body["name"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *PatchSuite) TestMergePatchInvalidPerson(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", 1, models.AnyVersion).Return(s.newPerson(), nil)
+	// act
+	res, body := s.patch(t, useCase, "application/merge-patch+json", `{"age": 200, "work": 1}`)
+	// assert
+	t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	t.Require().Equal("urn:problem-type:persons:invalid-request", body["type"])
+	t.Require().Contains(body["errors"], "work")
+	useCase.AssertExpectations(t)
+}
+
+func (s *PatchSuite) TestJSONPatch(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", 1, models.AnyVersion).Return(s.newPerson(), nil)
+	// act
+	res, body := s.patch(t, useCase, "application/json-patch+json", `[
+		{"op": "test", "path": "/name", "value": "Aboba"},
+		{"op": "replace", "path": "/name", "value": "Biba"},
+		{"op": "remove", "path": "/address"}
+	]`)
+	// assert
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().Equal("Biba", body["name"])
+	t.Require().EqualValues(42, body["age"])
+	t.Require().Empty(body["address"])
+	t.Require().Equal("Work", body["work"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *PatchSuite) TestJSONPatchRemoveAge(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", 1, models.AnyVersion).Return(s.newPerson(), nil)
+	// act
+	res, body := s.patch(t, useCase, "application/json-patch+json", `[
+		{"op": "test", "path": "/age", "value": 42},
+		{"op": "remove", "path": "/age"}
+	]`)
+	// assert
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().Contains(body, "age")
+	t.Require().Nil(body["age"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *PatchSuite) TestJSONPatchTestFailed(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", 1, models.AnyVersion).Return(s.newPerson(), nil)
+	// act
+	res, body := s.patch(t, useCase, "application/json-patch+json", `[
+		{"op": "test", "path": "/age", "value": 41},
+		{"op": "replace", "path": "/age", "value": 43}
+	]`)
+	// assert
+	t.Require().Equal(http.StatusConflict, res.StatusCode)
+	t.Require().Equal("urn:problem-type:persons:patch-test-failed", body["type"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *PatchSuite) TestJSONPatchInvalidValue(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", 1, models.AnyVersion).Return(s.newPerson(), nil)
+	// act
+	res, body := s.patch(t, useCase, "application/json-patch+json", `[{"op": "replace", "path": "/age", "value": "old"}]`)
+	// assert
+	t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	t.Require().Contains(body["errors"], "/0/value")
+	useCase.AssertExpectations(t)
+}
+
+func (s *PatchSuite) TestJSONPatchInvalidOperations(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	// act
+	res, body := s.patch(t, useCase, "application/json-patch+json", `[
+		{"op": "replace", "path": "name", "value": "Biba"},
+		{"op": "remove", "path": "/name"},
+		{"op": "add", "path": "/name", "value": null}
+	]`)
+	// assert
+	t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	t.Require().Equal(map[string]any{
+		"/0/path":  `unknown path "name"`,
+		"/1/path":  `required field "/name" cannot be removed`,
+		"/2/value": "must not be null",
+	}, body["errors"])
+	useCase.AssertNotCalled(t, "PatchPerson", mock.Anything, mock.Anything)
+}
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(PatchSuite))
+}
@@ -0,0 +1,224 @@
+package delivery
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/gofiber/fiber/v2"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const defaultCursorLimit = 100
+
+func parseOptionalInt(ctx *fiber.Ctx, key string, fieldErrors map[string]string) *int {
+	value := ctx.Query(key)
+	if value == "" {
+		return nil
+	}
+
+	res, err := strconv.Atoi(value)
+	if err != nil {
+		fieldErrors[key] = errors.FieldWrongType("an integer")
+		return nil
+	}
+
+	return &res
+}
+
+// parseSort parses comma-separated sort keys, e.g. "-age,name" (a leading minus means descending order).
+func parseSort(value string, fieldErrors map[string]string) []models.SortKey {
+	if value == "" {
+		return nil
+	}
+
+	fields := strings.Split(value, ",")
+	keys := make([]models.SortKey, 0, len(fields))
+
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		descending := strings.HasPrefix(field, "-")
+		key := models.SortKey{
+			Field:      models.PersonField(strings.TrimPrefix(field, "-")),
+			Descending: descending,
+		}
+
+		if !key.Field.Valid() {
+			fieldErrors["sort"] = "unknown sort field " + strconv.Quote(string(key.Field))
+			return nil
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func parsePersonsQuery(ctx *fiber.Ctx) (models.PersonsQuery, error) {
+	keyset := ctx.Request().URI().QueryArgs().Has("cursor")
+
+	defaultLimit := int64(math.MaxInt64)
+	if keyset {
+		defaultLimit = defaultCursorLimit
+	}
+
+	fieldErrors := make(map[string]string)
+
+	offset := parseNonNegativeInt(ctx, "offset", 0, fieldErrors)
+	limit := parseNonNegativeInt(ctx, "limit", defaultLimit, fieldErrors)
+
+	query := models.PersonsQuery{
+		Filter: models.PersonsFilter{
+			NameContains:    ctx.Query("name"),
+			NamePrefix:      ctx.Query("name_prefix"),
+			Work:            ctx.Query("work"),
+			AgeMin:          parseOptionalInt(ctx, "age_min", fieldErrors),
+			AgeMax:          parseOptionalInt(ctx, "age_max", fieldErrors),
+			AddressContains: ctx.Query("address"),
+			Deleted:         models.DeletedFilter(ctx.Query("deleted")),
+		},
+		Sort:      parseSort(ctx.Query("sort"), fieldErrors),
+		Offset:    offset,
+		Limit:     limit,
+		Keyset:    keyset,
+		WithTotal: envelopeRequested(ctx),
+	}
+
+	if token := ctx.Query("cursor"); token != "" {
+		var err error
+		query.Cursor, err = decodeCursor(token, ctx.Query("sort"))
+		if err != nil {
+			fieldErrors["cursor"] = "invalid cursor: " + err.Error()
+		}
+	}
+
+	if !query.Filter.Deleted.Valid() {
+		fieldErrors["deleted"] = "must be one of only, include"
+	}
+
+	if query.Filter.AgeMin != nil && query.Filter.AgeMax != nil && *query.Filter.AgeMin > *query.Filter.AgeMax {
+		fieldErrors["age_min"] = "must not be greater than age_max"
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.PersonsQuery{}, errors.ErrInvalidPersonsQuery(fieldErrors)
+	}
+
+	return query, nil
+}
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 1000
+)
+
+func parseNonNegativeInt(ctx *fiber.Ctx, key string, defaultValue int64, fieldErrors map[string]string) int64 {
+	value := ctx.Query(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	res, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || res < 0 {
+		fieldErrors[key] = "must be a non-negative integer"
+		return defaultValue
+	}
+
+	return res
+}
+
+func parsePersonHistoryQuery(ctx *fiber.Ctx, personID int) (models.PersonHistoryQuery, error) {
+	fieldErrors := make(map[string]string)
+
+	query := models.PersonHistoryQuery{
+		PersonID: personID,
+		Offset:   parseNonNegativeInt(ctx, "offset", 0, fieldErrors),
+		Limit:    parseNonNegativeInt(ctx, "limit", defaultHistoryLimit, fieldErrors),
+	}
+
+	if query.Limit > maxHistoryLimit {
+		fieldErrors["limit"] = "must not be greater than " + strconv.Itoa(maxHistoryLimit)
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.PersonHistoryQuery{}, errors.ErrInvalidHistoryQuery(fieldErrors)
+	}
+
+	return query, nil
+}
+
+const (
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 100
+	maxSearchQueryLength = 256
+)
+
+func parsePersonsSearchQuery(ctx *fiber.Ctx) (models.PersonsSearchQuery, error) {
+	fieldErrors := make(map[string]string)
+
+	query := models.PersonsSearchQuery{
+		Query:  strings.TrimSpace(ctx.Query("q")),
+		Offset: parseNonNegativeInt(ctx, "offset", 0, fieldErrors),
+		Limit:  parseNonNegativeInt(ctx, "limit", defaultSearchLimit, fieldErrors),
+	}
+
+	if query.Query == "" {
+		fieldErrors["q"] = errors.FieldRequired
+	} else {
+		validateLength(fieldErrors, "q", query.Query, maxSearchQueryLength)
+	}
+
+	if query.Limit > maxSearchLimit {
+		fieldErrors["limit"] = "must not be greater than " + strconv.Itoa(maxSearchLimit)
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.PersonsSearchQuery{}, errors.ErrInvalidPersonsQuery(fieldErrors)
+	}
+
+	return query, nil
+}
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+func parseNameSuggestionQuery(ctx *fiber.Ctx) (models.NameSuggestionQuery, error) {
+	fieldErrors := make(map[string]string)
+
+	query := models.NameSuggestionQuery{
+		Name:  strings.TrimSpace(ctx.Query("name")),
+		Mode:  models.NameSuggestionMode(ctx.Query("mode", string(models.SuggestFuzzy))),
+		Limit: parseNonNegativeInt(ctx, "limit", defaultSuggestLimit, fieldErrors),
+	}
+
+	if query.Name == "" {
+		fieldErrors["name"] = errors.FieldRequired
+	} else {
+		validateLength(fieldErrors, "name", query.Name, maxNameLength)
+	}
+
+	if query.Mode != models.SuggestFuzzy && query.Mode != models.SuggestPrefix {
+		fieldErrors["mode"] = "must be one of fuzzy, prefix"
+	}
+
+	if query.Limit > maxSuggestLimit {
+		fieldErrors["limit"] = "must not be greater than " + strconv.Itoa(maxSuggestLimit)
+	}
+
+	if value := ctx.Query("threshold"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			fieldErrors["threshold"] = "must be a number greater than 0 and not greater than 1"
+		}
+
+		query.Threshold = threshold
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.NameSuggestionQuery{}, errors.ErrInvalidPersonsQuery(fieldErrors)
+	}
+
+	return query, nil
+}
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	personv1 "github.com/Inspirate789/ds-lab1/api/person/v1"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newPersonMessage(person models.Person) *personv1.Person {
+	message := &personv1.Person{
+		Id: int64(person.ID),
+		Properties: &personv1.PersonProperties{
+			Name:    person.Name,
+			Address: person.Address,
+			Work:    person.Work,
+		},
+		Version: person.Version,
+	}
+
+	if person.Age != nil {
+		message.Properties.Age = proto.Int32(int32(*person.Age))
+	}
+
+	if person.DeletedAt != nil {
+		message.DeletedAt = timestamppb.New(*person.DeletedAt)
+	}
+
+	return message
+}
+
+// newPersonPropertiesDTO converts the message to the DTO of the REST API, so the properties are validated the same way.
+func newPersonPropertiesDTO(properties *personv1.PersonProperties) delivery.PersonProperties {
+	dto := delivery.PersonProperties{
+		Name:    properties.GetName(),
+		Address: properties.GetAddress(),
+		Work:    properties.GetWork(),
+	}
+
+	if properties.Age != nil {
+		age := int(properties.GetAge())
+		dto.Age = &age
+	}
+
+	return dto
+}
@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"maps"
+	"slices"
+)
+
+// statusCodes maps the statuses of the REST API onto the codes of gRPC,
+// so both APIs report the errors of the use cases the same way.
+var statusCodes = map[int]codes.Code{
+	fiber.StatusBadRequest:          codes.InvalidArgument,
+	fiber.StatusNotFound:            codes.NotFound,
+	fiber.StatusConflict:            codes.Aborted,
+	fiber.StatusPreconditionFailed:  codes.FailedPrecondition,
+	fiber.StatusUnprocessableEntity: codes.FailedPrecondition,
+	fiber.StatusServiceUnavailable:  codes.Unavailable,
+}
+
+// statusError returns the gRPC status of the error. The errors of the fields are sent as google.rpc.BadRequest
+// details. Internal errors are logged only and the client gets the generic message.
+func (s *server) statusError(err error) error {
+	details, ok := errors.FromError(err)
+	if !ok {
+		s.logger.Error(err.Error())
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	s.logger.Warn(err.Error())
+
+	code, ok := statusCodes[details.Status]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	st := status.New(code, details.Detail)
+
+	fieldErrors, _ := details.Extensions["errors"].(map[string]string)
+	if len(fieldErrors) == 0 {
+		return st.Err()
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range slices.Sorted(maps.Keys(fieldErrors)) {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: fieldErrors[field],
+		})
+	}
+
+	withDetails, err := st.WithDetails(badRequest)
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
@@ -0,0 +1,214 @@
+// Package rpc implements the gRPC API of persons on top of the use cases of the REST API.
+package rpc
+
+import (
+	"context"
+	personv1 "github.com/Inspirate789/ds-lab1/api/person/v1"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"google.golang.org/grpc"
+	"log/slog"
+	"math"
+)
+
+type server struct {
+	personv1.UnimplementedPersonServiceServer
+	useCase delivery.UseCase
+	logger  *slog.Logger
+}
+
+func Register(registrar grpc.ServiceRegistrar, useCase delivery.UseCase, logger *slog.Logger) {
+	personv1.RegisterPersonServiceServer(registrar, &server{
+		useCase: useCase,
+		logger:  logger,
+	})
+}
+
+func parsePersonID(id int64) (int, error) {
+	if id <= 0 || int64(int(id)) != id {
+		return 0, errors.ErrInvalidID
+	}
+
+	return int(id), nil
+}
+
+func (s *server) GetPerson(ctx context.Context, req *personv1.GetPersonRequest) (*personv1.GetPersonResponse, error) {
+	personID, err := parsePersonID(req.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	person, err := s.useCase.GetPerson(ctx, personID)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	return &personv1.GetPersonResponse{Person: newPersonMessage(person)}, nil
+}
+
+// ListPersons sends the persons one by one. Sends block while the client does not receive them,
+// so persons are not read from the database faster than the client receives them.
+func (s *server) ListPersons(req *personv1.ListPersonsRequest, stream personv1.PersonService_ListPersonsServer) error {
+	query, err := newPersonsQuery(req)
+	if err != nil {
+		return s.statusError(err)
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	for person, err := range s.useCase.StreamPersons(ctx, query) {
+		if err != nil {
+			return s.statusError(err)
+		}
+
+		err = stream.Send(&personv1.ListPersonsResponse{Person: newPersonMessage(person)})
+		if err != nil {
+			cancel() // the rest of the rows must not be read after the client is gone
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *server) CreatePerson(
+	ctx context.Context,
+	req *personv1.CreatePersonRequest,
+) (*personv1.CreatePersonResponse, error) {
+	properties := newPersonPropertiesDTO(req.GetPerson())
+
+	err := properties.Validate(false)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	person, err := s.useCase.CreatePerson(ctx, properties.ToProperties())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	return &personv1.CreatePersonResponse{Person: newPersonMessage(person)}, nil
+}
+
+func (s *server) UpdatePerson(
+	ctx context.Context,
+	req *personv1.UpdatePersonRequest,
+) (*personv1.UpdatePersonResponse, error) {
+	personID, err := parsePersonID(req.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	properties := newPersonPropertiesDTO(req.GetPerson())
+
+	err = properties.Validate(true)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	person, err := s.useCase.UpdatePerson(ctx, properties.ToPerson(personID), req.GetExpectedVersion())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	return &personv1.UpdatePersonResponse{Person: newPersonMessage(person)}, nil
+}
+
+func (s *server) DeletePerson(
+	ctx context.Context,
+	req *personv1.DeletePersonRequest,
+) (*personv1.DeletePersonResponse, error) {
+	personID, err := parsePersonID(req.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	err = s.useCase.DeletePerson(ctx, personID, req.GetExpectedVersion())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	return &personv1.DeletePersonResponse{}, nil
+}
+
+// Health reports whether the service is ready to serve requests like the readiness probe of the REST API.
+func (s *server) Health(ctx context.Context, _ *personv1.HealthRequest) (*personv1.HealthResponse, error) {
+	err := s.useCase.HealthCheck(ctx)
+	if err != nil {
+		s.logger.Warn("service is not ready", slog.String("error", err.Error()))
+		return &personv1.HealthResponse{Status: personv1.HealthResponse_SERVING_STATUS_NOT_SERVING}, nil
+	}
+
+	return &personv1.HealthResponse{Status: personv1.HealthResponse_SERVING_STATUS_SERVING}, nil
+}
+
+var deletedFilters = map[personv1.DeletedFilter]models.DeletedFilter{
+	personv1.DeletedFilter_DELETED_FILTER_UNSPECIFIED: models.DeletedExclude,
+	personv1.DeletedFilter_DELETED_FILTER_ONLY:        models.DeletedOnly,
+	personv1.DeletedFilter_DELETED_FILTER_INCLUDE:     models.DeletedInclude,
+}
+
+func newPersonsQuery(req *personv1.ListPersonsRequest) (models.PersonsQuery, error) {
+	fieldErrors := make(map[string]string)
+
+	query := models.PersonsQuery{
+		Filter: models.PersonsFilter{
+			NameContains:    req.GetNameContains(),
+			NamePrefix:      req.GetNamePrefix(),
+			Work:            req.GetWork(),
+			AddressContains: req.GetAddressContains(),
+		},
+		Offset: req.GetOffset(),
+		Limit:  req.GetLimit(),
+	}
+
+	if req.AgeMin != nil {
+		ageMin := int(req.GetAgeMin())
+		query.Filter.AgeMin = &ageMin
+	}
+
+	if req.AgeMax != nil {
+		ageMax := int(req.GetAgeMax())
+		query.Filter.AgeMax = &ageMax
+	}
+
+	if query.Filter.AgeMin != nil && query.Filter.AgeMax != nil && *query.Filter.AgeMin > *query.Filter.AgeMax {
+		fieldErrors["age_min"] = "must not be greater than age_max"
+	}
+
+	deleted, ok := deletedFilters[req.GetDeleted()]
+	if !ok {
+		fieldErrors["deleted"] = "unknown filter"
+	}
+
+	query.Filter.Deleted = deleted
+
+	for _, key := range req.GetSort() {
+		field := models.PersonField(key.GetField())
+		if !field.Valid() {
+			fieldErrors["sort"] = "unknown sort field " + key.GetField()
+			break
+		}
+
+		query.Sort = append(query.Sort, models.SortKey{Field: field, Descending: key.GetDescending()})
+	}
+
+	if query.Offset < 0 {
+		fieldErrors["offset"] = "must be a non-negative integer"
+	}
+
+	switch {
+	case query.Limit < 0:
+		fieldErrors["limit"] = "must be a non-negative integer"
+	case query.Limit == 0:
+		query.Limit = math.MaxInt64
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.PersonsQuery{}, errors.ErrInvalidPersonsQuery(fieldErrors)
+	}
+
+	return query, nil
+}
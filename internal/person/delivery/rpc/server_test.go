@@ -0,0 +1,237 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	personv1 "github.com/Inspirate789/ds-lab1/api/person/v1"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/rpc"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"iter"
+	"log/slog"
+	"math"
+	"net"
+	"testing"
+)
+
+type ServerSuite struct {
+	suite.Suite
+}
+
+// newClient serves the use cases in memory and returns the client connected to them.
+func (*ServerSuite) newClient(t provider.T, useCase *UseCaseMock) personv1.PersonServiceClient {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	rpc.Register(grpcServer, useCase, logger)
+
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	t.Require().NoError(err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		grpcServer.Stop()
+	})
+
+	return personv1.NewPersonServiceClient(conn)
+}
+
+// fieldViolations returns the descriptions of the invalid fields sent in the details of the status.
+func (*ServerSuite) fieldViolations(st *status.Status) map[string]string {
+	violations := make(map[string]string)
+
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+
+		for _, violation := range badRequest.GetFieldViolations() {
+			violations[violation.GetField()] = violation.GetDescription()
+		}
+	}
+
+	return violations
+}
+
+func (s *ServerSuite) TestGetPersonWithLargeID(t provider.T) {
+	t.Epic("gRPC")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	const personID = math.MaxInt32 + 1
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", personID).Return(models.Person{ID: personID, Version: math.MaxInt32 + 2}, nil)
+	client := s.newClient(t, useCase)
+	// act
+	res, err := client.GetPerson(context.Background(), &personv1.GetPersonRequest{Id: personID})
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(int64(personID), res.GetPerson().GetId())
+	t.Require().Equal(int64(math.MaxInt32+2), res.GetPerson().GetVersion())
+	useCase.AssertExpectations(t)
+}
+
+func (s *ServerSuite) TestStatusCodes(t provider.T) {
+	t.Epic("gRPC")
+	t.Severity(allure.CRITICAL)
+
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{"not found", usecase.NotFound(models.ErrPersonNotFound), codes.NotFound, "person not found"},
+		{"version mismatch", usecase.Conflict(models.ErrVersionMismatch), codes.FailedPrecondition, "person was modified by another request"},
+		{"conflict", usecase.Conflict(errors.New("serialization failure")), codes.Aborted, "request conflicts with a concurrent change, retry it"},
+		{"unavailable", usecase.Unavailable(errors.New("connection refused")), codes.Unavailable, "storage is temporarily unavailable, retry later"},
+		{"internal", errors.New("pq: password authentication failed"), codes.Internal, "internal server error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			useCase.On("DeletePerson", 1, int64(2)).Return(test.err)
+			client := s.newClient(t, useCase)
+			// act
+			_, err := client.DeletePerson(context.Background(), &personv1.DeletePersonRequest{Id: 1, ExpectedVersion: 2})
+			// assert
+			st, ok := status.FromError(err)
+			t.Require().True(ok)
+			t.Require().Equal(test.code, st.Code())
+			t.Require().Equal(test.message, st.Message())
+			t.Require().Empty(st.Details())
+		})
+	}
+}
+
+func (s *ServerSuite) TestInvalidID(t provider.T) {
+	t.Epic("gRPC")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	client := s.newClient(t, useCase)
+	// act
+	_, err := client.GetPerson(context.Background(), &personv1.GetPersonRequest{Id: -1})
+	// assert
+	t.Require().Equal(codes.InvalidArgument, status.Code(err))
+	useCase.AssertNotCalled(t, "GetPerson", mock.Anything)
+}
+
+func (s *ServerSuite) TestCreateInvalidPerson(t provider.T) {
+	t.Epic("gRPC")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	client := s.newClient(t, useCase)
+	// act
+	_, err := client.CreatePerson(context.Background(), &personv1.CreatePersonRequest{
+		Person: &personv1.PersonProperties{Age: proto.Int32(200)},
+	})
+	// assert
+	st := status.Convert(err)
+	t.Require().Equal(codes.InvalidArgument, st.Code())
+	t.Require().Equal(map[string]string{
+		"name": "is required",
+		"age":  "must be between 0 and 150",
+	}, s.fieldViolations(st))
+	useCase.AssertNotCalled(t, "CreatePerson", mock.Anything)
+}
+
+func (s *ServerSuite) TestListPersonsInvalidQuery(t provider.T) {
+	t.Epic("gRPC")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	ageMin, ageMax := int32(30), int32(20)
+	useCase := new(UseCaseMock)
+	client := s.newClient(t, useCase)
+	// act
+	stream, err := client.ListPersons(context.Background(), &personv1.ListPersonsRequest{
+		AgeMin:  &ageMin,
+		AgeMax:  &ageMax,
+		Deleted: personv1.DeletedFilter(42),
+		Sort:    []*personv1.SortKey{{Field: "salary"}},
+		Offset:  -1,
+		Limit:   -1,
+	})
+	t.Require().NoError(err)
+	_, err = stream.Recv()
+	// assert
+	st := status.Convert(err)
+	t.Require().Equal(codes.InvalidArgument, st.Code())
+	t.Require().Equal(map[string]string{
+		"age_min": "must not be greater than age_max",
+		"deleted": "unknown filter",
+		"sort":    "unknown sort field salary",
+		"offset":  "must be a non-negative integer",
+		"limit":   "must be a non-negative integer",
+	}, s.fieldViolations(st))
+	useCase.AssertNotCalled(t, "StreamPersons", mock.Anything)
+}
+
+func (s *ServerSuite) TestListPersonsQuery(t provider.T) {
+	t.Epic("gRPC")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	ageMin := int32(18)
+	useCase := new(UseCaseMock)
+	query := mock.MatchedBy(func(query models.PersonsQuery) bool {
+		return query.Limit == math.MaxInt64 && query.Filter.AgeMin != nil && *query.Filter.AgeMin == 18 &&
+			query.Filter.Deleted == models.DeletedInclude && len(query.Sort) == 1 && query.Sort[0].Descending
+	})
+	useCase.On("StreamPersons", query).Return(iter.Seq2[models.Person, error](func(yield func(models.Person, error) bool) {
+		yield(models.Person{ID: 1}, nil)
+	}))
+	client := s.newClient(t, useCase)
+	// act
+	stream, err := client.ListPersons(context.Background(), &personv1.ListPersonsRequest{
+		AgeMin:  &ageMin,
+		Deleted: personv1.DeletedFilter_DELETED_FILTER_INCLUDE,
+		Sort:    []*personv1.SortKey{{Field: "age", Descending: true}},
+	})
+	t.Require().NoError(err)
+	res, err := stream.Recv()
+	t.Require().NoError(err)
+	_, err = stream.Recv()
+	// assert
+	t.Require().Equal(int64(1), res.GetPerson().GetId())
+	t.Require().ErrorIs(err, io.EOF)
+	useCase.AssertExpectations(t)
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(ServerSuite))
+}
@@ -0,0 +1,35 @@
+package rpc_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/stretchr/testify/mock"
+	"iter"
+)
+
+// UseCaseMock mocks the use cases called by the tests, the other use cases are not implemented.
+type UseCaseMock struct {
+	delivery.UseCase
+	mock.Mock
+}
+
+func (u *UseCaseMock) GetPerson(_ context.Context, personID int) (models.Person, error) {
+	args := u.Called(personID)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) StreamPersons(_ context.Context, query models.PersonsQuery) iter.Seq2[models.Person, error] {
+	args := u.Called(query)
+	return args.Get(0).(iter.Seq2[models.Person, error])
+}
+
+func (u *UseCaseMock) CreatePerson(_ context.Context, person models.PersonProperties) (models.Person, error) {
+	args := u.Called(person)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) DeletePerson(_ context.Context, personID int, expectedVersion int64) error {
+	args := u.Called(personID, expectedVersion)
+	return args.Error(0)
+}
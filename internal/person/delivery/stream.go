@@ -0,0 +1,94 @@
+package delivery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/gofiber/fiber/v2"
+	"log/slog"
+)
+
+const (
+	mimeNDJSON = "application/x-ndjson"
+	// streamFlushInterval is the number of persons written to the buffer between flushes to the client.
+	streamFlushInterval = 100
+)
+
+func ndjsonRequested(ctx *fiber.Ctx) bool {
+	return ctx.Accepts(fiber.MIMEApplicationJSON, mimeNDJSON) == mimeNDJSON
+}
+
+// streamContext returns the context of the stream writer. It is canceled with the user context of the request
+// and when the server stops serving the request, so the stream stops before it fails to flush.
+func streamContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	streamCtx, cancel := context.WithCancel(ctx.UserContext())
+	stop := context.AfterFunc(ctx.Context(), cancel)
+
+	return streamCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// streamPersons writes the persons matching the query one by one. Flushes block while the client
+// does not read the response, so persons are not read from the database faster than the client receives them.
+// The query is canceled if the client disconnects, the iteration stops once the context is done.
+func (d *delivery) streamPersons(
+	ctx context.Context,
+	query models.PersonsQuery,
+	write func(person models.Person) error,
+	flush func() error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	written := 0
+
+	for person, err := range d.useCase.StreamPersons(ctx, query) {
+		if err == nil {
+			err = ctx.Err()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = write(person)
+		if err == nil && written%streamFlushInterval == streamFlushInterval-1 {
+			err = flush()
+		}
+
+		if err != nil {
+			cancel() // the rest of the rows must not be read after the client is gone
+			return err
+		}
+
+		written++
+	}
+
+	return flush()
+}
+
+// sendPersonsStream sends persons as newline delimited JSON. The stream writer is called after the handler returns,
+// so the fiber context must not be used there.
+func (d *delivery) sendPersonsStream(ctx *fiber.Ctx, query models.PersonsQuery) error {
+	streamCtx, cancel := streamContext(ctx)
+
+	ctx.Set(fiber.HeaderContentType, mimeNDJSON)
+	bodystream.SetWriter(ctx, func(w *bufio.Writer) {
+		defer cancel()
+
+		encoder := json.NewEncoder(w)
+
+		err := d.streamPersons(streamCtx, query, func(person models.Person) error {
+			return encoder.Encode(NewPersonDTO(person))
+		}, w.Flush)
+		if err != nil {
+			d.logger.Error("failed to stream persons", slog.String("error", err.Error()))
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package delivery_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type StreamSuite struct {
+	suite.Suite
+}
+
+// newApp returns the app serving the requests with the given user context.
+func (*StreamSuite) newApp(ctx context.Context, useCase *UseCaseMock) *fiber.App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New()
+	app.Use(bodystream.Middleware)
+	app.Use(func(fiberCtx *fiber.Ctx) error {
+		fiberCtx.SetUserContext(ctx)
+		return fiberCtx.Next()
+	})
+	delivery.AddHandlers(app, delivery.Config{}, useCase, logger)
+
+	return app
+}
+
+// persons returns the endless sequence of persons and the counter of the yielded ones.
+func (*StreamSuite) persons() (iter.Seq2[models.Person, error], *int) {
+	yielded := 0
+
+	return func(yield func(models.Person, error) bool) {
+		for {
+			yielded++
+			if !yield(models.Person{ID: yielded, PersonProperties: models.PersonProperties{Name: "Aboba"}}, nil) {
+				return
+			}
+		}
+	}, &yielded
+}
+
+func (s *StreamSuite) TestStopsWhenContextIsDone(t provider.T) {
+	t.Epic("Streaming")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	persons, yielded := s.persons()
+	useCase := new(UseCaseMock)
+	useCase.On("StreamPersons", mock.Anything).Return(persons)
+	app := s.newApp(ctx, useCase)
+	req := httptest.NewRequest(http.MethodGet, "/persons", nil)
+	req.Header.Set(fiber.HeaderAccept, "application/x-ndjson")
+	// act
+	res, err := app.Test(req, -1)
+	t.Require().NoError(err)
+	body, err := io.ReadAll(res.Body)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+	t.Require().Empty(body)
+	t.Require().Equal(1, *yielded)
+	useCase.AssertExpectations(t)
+}
+
+func (s *StreamSuite) TestExportStopsWhenContextIsDone(t provider.T) {
+	t.Epic("Streaming")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	persons, yielded := s.persons()
+	useCase := new(UseCaseMock)
+	useCase.On("StreamPersons", mock.Anything).Return(persons)
+	app := s.newApp(ctx, useCase)
+	// act
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/persons/export.csv", nil), -1)
+	t.Require().NoError(err)
+	body, err := io.ReadAll(res.Body)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal("id,name,age,address,work\n", string(body))
+	t.Require().Equal(1, *yielded)
+	useCase.AssertExpectations(t)
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(StreamSuite))
+}
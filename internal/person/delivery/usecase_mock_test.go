@@ -0,0 +1,65 @@
+package delivery_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/stretchr/testify/mock"
+	"iter"
+)
+
+// UseCaseMock mocks the use cases called by the tests, the other use cases are not implemented.
+// PatchPerson applies the patch to the person passed to Return, as the use case does in the transaction.
+type UseCaseMock struct {
+	delivery.UseCase
+	mock.Mock
+}
+
+func (u *UseCaseMock) PatchPerson(
+	_ context.Context,
+	personID int,
+	patch models.PersonPatch,
+	expectedVersion int64,
+) (models.Person, error) {
+	args := u.Called(personID, expectedVersion)
+	if err := args.Error(1); err != nil {
+		return models.Person{}, err
+	}
+
+	return patch.Apply(args.Get(0).(models.Person))
+}
+
+func (u *UseCaseMock) GetPerson(_ context.Context, personID int) (models.Person, error) {
+	args := u.Called(personID)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) ReplacePerson(
+	_ context.Context,
+	person models.Person,
+	expectedVersion int64,
+	createIfAbsent bool,
+) (models.Person, bool, error) {
+	args := u.Called(person, expectedVersion, createIfAbsent)
+	return args.Get(0).(models.Person), args.Bool(1), args.Error(2)
+}
+
+func (u *UseCaseMock) DeletePerson(_ context.Context, personID int, expectedVersion int64) error {
+	args := u.Called(personID, expectedVersion)
+	return args.Error(0)
+}
+
+func (u *UseCaseMock) StreamPersons(_ context.Context, query models.PersonsQuery) iter.Seq2[models.Person, error] {
+	args := u.Called(query)
+	return args.Get(0).(iter.Seq2[models.Person, error])
+}
+
+func (u *UseCaseMock) CreatePersons(_ context.Context, persons []models.PersonProperties) ([]models.Person, error) {
+	args := u.Called(persons)
+	return args.Get(0).([]models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) CreatePerson(_ context.Context, person models.PersonProperties) (models.Person, error) {
+	args := u.Called(person)
+	return args.Get(0).(models.Person), args.Error(1)
+}
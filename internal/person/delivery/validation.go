@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLength    = 255
+	maxAddressLength = 512
+	maxWorkLength    = 255
+	minAge           = 0
+	maxAge           = 150
+)
+
+func decodePersonProperties(body []byte) (PersonProperties, error) {
+	var dto PersonProperties
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&dto)
+	if err != nil {
+		return PersonProperties{}, newDecodeError(err)
+	}
+
+	if decoder.More() {
+		return PersonProperties{}, newDecodeError(stderrors.New("unexpected data after JSON object"))
+	}
+
+	return dto, nil
+}
+
+func newDecodeError(err error) errors.ValidationError {
+	fieldErrors, err := decodeErrorFields(err)
+	// the errors of the fields are reported like the violations of the specification
+	if len(fieldErrors) != 0 {
+		return errors.ErrInvalidPersonFields(fieldErrors)
+	}
+
+	return errors.ValidationError{
+		Message: errors.ErrInvalidPerson(err.Error()).Error(),
+		Errors:  fieldErrors,
+	}
+}
+
+// decodeErrorFields returns the errors of the fields reported by the JSON decoder
+// and the decoder error with the readable message.
+func decodeErrorFields(err error) (map[string]string, error) {
+	fieldErrors := make(map[string]string)
+
+	var typeErr *json.UnmarshalTypeError
+	if stderrors.As(err, &typeErr) && typeErr.Field != "" {
+		fieldErrors[typeErr.Field] = errors.FieldWrongType(jsonTypeName(typeErr.Type.Kind()))
+	}
+
+	const unknownFieldPrefix = "json: unknown field "
+	if msg := err.Error(); strings.HasPrefix(msg, unknownFieldPrefix) {
+		field := strings.Trim(strings.TrimPrefix(msg, unknownFieldPrefix), `"`)
+		fieldErrors[field] = errors.FieldUnknown
+	}
+
+	if stderrors.Is(err, io.EOF) {
+		err = stderrors.New("empty body")
+	}
+
+	return fieldErrors, err
+}
+
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.String:
+		return "a string"
+	default:
+		return "a " + kind.String()
+	}
+}
+
+func validateLength(fieldErrors map[string]string, field, value string, maxLength int) {
+	if utf8.RuneCountInString(value) > maxLength {
+		fieldErrors[field] = errors.FieldTooLong(maxLength)
+	}
+}
+
+// Validate checks the person properties. Partial properties (e.g. from PATCH request)
+// may omit the name, because empty fields are not updated.
+func (p PersonProperties) Validate(partial bool) error {
+	fieldErrors := make(map[string]string)
+
+	if !partial && strings.TrimSpace(p.Name) == "" {
+		fieldErrors["name"] = errors.FieldRequired
+	} else {
+		validateLength(fieldErrors, "name", p.Name, maxNameLength)
+	}
+
+	if p.Age != nil && (*p.Age < minAge || *p.Age > maxAge) {
+		fieldErrors["age"] = errors.FieldOutOfRange(minAge, maxAge)
+	}
+
+	validateLength(fieldErrors, "address", p.Address, maxAddressLength)
+	validateLength(fieldErrors, "work", p.Work, maxWorkLength)
+
+	if len(fieldErrors) != 0 {
+		return errors.ErrInvalidPersonFields(fieldErrors)
+	}
+
+	return nil
+}
+
+func parsePersonProperties(body []byte, partial bool) (PersonProperties, error) {
+	dto, err := decodePersonProperties(body)
+	if err != nil {
+		return PersonProperties{}, err
+	}
+
+	err = dto.Validate(partial)
+	if err != nil {
+		return PersonProperties{}, err
+	}
+
+	return dto, nil
+}
@@ -0,0 +1,162 @@
+package delivery_test
+
+import (
+	"encoding/json"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type ValidationSuite struct {
+	suite.Suite
+}
+
+// send creates the person from the body and returns the response with the decoded problem.
+func (*ValidationSuite) send(t provider.T, useCase *UseCaseMock, body string) (*http.Response, map[string]any) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New()
+	delivery.AddHandlers(app, delivery.Config{}, useCase, logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	res, err := app.Test(req, -1)
+	t.Require().NoError(err)
+
+	defer res.Body.Close()
+
+	var resBody map[string]any
+	if res.StatusCode >= http.StatusBadRequest {
+		t.Require().NoError(json.NewDecoder(res.Body).Decode(&resBody))
+	}
+
+	return res, resBody
+}
+
+func (s *ValidationSuite) TestInvalidPerson(t provider.T) {
+	t.Epic("Validation")
+	t.Severity(allure.CRITICAL)
+
+	tests := map[string]struct {
+		body   string
+		errors map[string]any
+	}{
+		"unknown field": {
+			body:   `{"name": "Aboba", "foo": 1}`,
+			errors: map[string]any{"foo": "unknown field"},
+		},
+		"missing name": {
+			body:   `{"age": 20}`,
+			errors: map[string]any{"name": "is required"},
+		},
+		"blank name": {
+			body:   `{"name": "   "}`,
+			errors: map[string]any{"name": "is required"},
+		},
+		"too long name": {
+			body:   `{"name": "` + strings.Repeat("a", 256) + `"}`,
+			errors: map[string]any{"name": "must be at most 255 characters long"},
+		},
+		"too long multibyte name": {
+			body:   `{"name": "` + strings.Repeat("ж", 256) + `"}`,
+			errors: map[string]any{"name": "must be at most 255 characters long"},
+		},
+		"too long address": {
+			body:   `{"name": "Aboba", "address": "` + strings.Repeat("ж", 513) + `"}`,
+			errors: map[string]any{"address": "must be at most 512 characters long"},
+		},
+		"too long work": {
+			body:   `{"name": "Aboba", "work": "` + strings.Repeat("ж", 256) + `"}`,
+			errors: map[string]any{"work": "must be at most 255 characters long"},
+		},
+		"negative age": {
+			body:   `{"name": "Aboba", "age": -1}`,
+			errors: map[string]any{"age": "must be between 0 and 150"},
+		},
+		"too large age": {
+			body:   `{"name": "Aboba", "age": 151}`,
+			errors: map[string]any{"age": "must be between 0 and 150"},
+		},
+		"age of wrong type": {
+			body:   `{"name": "Aboba", "age": "20"}`,
+			errors: map[string]any{"age": "must be an integer"},
+		},
+		"several fields": {
+			body: `{"name": "", "age": 151, "work": "` + strings.Repeat("a", 256) + `"}`,
+			errors: map[string]any{
+				"name": "is required",
+				"age":  "must be between 0 and 150",
+				"work": "must be at most 255 characters long",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			// act
+			res, body := s.send(t, useCase, test.body)
+			// assert
+			t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+			t.Require().Equal("invalid person data", body["detail"])
+			t.Require().Equal(test.errors, body["errors"])
+			useCase.AssertNotCalled(t, "CreatePerson", mock.Anything)
+		})
+	}
+}
+
+func (s *ValidationSuite) TestValidPerson(t provider.T) {
+	t.Epic("Validation")
+	t.Severity(allure.CRITICAL)
+
+	tests := map[string]models.PersonProperties{
+		"youngest":               {Name: "Aboba", Age: age(0)},
+		"oldest":                 {Name: "Aboba", Age: age(150)},
+		"without age":            {Name: "Aboba"},
+		"longest name":           {Name: strings.Repeat("a", 255)},
+		"longest multibyte name": {Name: strings.Repeat("ж", 255)},
+		"longest address":        {Name: "Aboba", Address: strings.Repeat("ж", 512)},
+		"longest work":           {Name: "Aboba", Work: strings.Repeat("ж", 255)},
+	}
+
+	for name, person := range tests {
+		t.Run(name, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			useCase.On("CreatePerson", person).Return(models.Person{ID: 1, PersonProperties: person}, nil)
+			requestBody, err := json.Marshal(delivery.PersonProperties{
+				Name:    person.Name,
+				Age:     person.Age,
+				Address: person.Address,
+				Work:    person.Work,
+			})
+			t.Require().NoError(err)
+			// act
+			res, _ := s.send(t, useCase, string(requestBody))
+			// assert
+			t.Require().Equal(http.StatusCreated, res.StatusCode)
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(ValidationSuite))
+}
@@ -0,0 +1,257 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type WebhookUseCase interface {
+	CreateWebhook(ctx context.Context, webhook models.WebhookProperties) (models.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, webhookID int) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID int) error
+	GetWebhookDeliveries(ctx context.Context, query models.WebhookDeliveriesQuery) (models.WebhookDeliveriesPage, error)
+}
+
+type webhookDelivery struct {
+	*delivery
+	webhooks WebhookUseCase
+}
+
+func AddWebhookHandlers(root fiber.Router, useCase WebhookUseCase, logger *slog.Logger) {
+	handler := &webhookDelivery{
+		delivery: &delivery{logger: logger},
+		webhooks: useCase,
+	}
+
+	api := root.Group("/webhooks")
+	api.Get("/", handler.GetWebhooks)
+	api.Post("/", handler.PostWebhook)
+	api.Get("/:webhookId", handler.GetWebhook)
+	api.Delete("/:webhookId", handler.DeleteWebhook)
+	api.Get("/:webhookId/deliveries", handler.GetWebhookDeliveries)
+}
+
+const (
+	maxWebhookURLLength    = 2048
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 255
+
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 1000
+)
+
+func decodeWebhookProperties(body []byte) (WebhookProperties, error) {
+	var dto WebhookProperties
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&dto)
+	if err == nil && decoder.More() {
+		err = stderrors.New("unexpected data after JSON object")
+	}
+
+	if err != nil {
+		fieldErrors, err := decodeErrorFields(err)
+		return WebhookProperties{}, errors.ErrInvalidWebhook(err.Error(), fieldErrors)
+	}
+
+	return dto, nil
+}
+
+func validateWebhookURL(value string) string {
+	if value == "" {
+		return errors.FieldRequired
+	}
+
+	if len(value) > maxWebhookURLLength {
+		return fmt.Sprintf("must be at most %d characters long", maxWebhookURLLength)
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "must be an absolute http or https URL"
+	}
+
+	return ""
+}
+
+// Validate checks the webhook properties. The events are deduplicated.
+func (w *WebhookProperties) Validate() error {
+	fieldErrors := make(map[string]string)
+
+	if msg := validateWebhookURL(w.URL); msg != "" {
+		fieldErrors["url"] = msg
+	}
+
+	if len(w.Events) == 0 {
+		fieldErrors["events"] = errors.FieldRequired
+	}
+
+	events := make([]string, 0, len(w.Events))
+
+	for _, event := range w.Events {
+		if !models.PersonEventType(event).Valid() {
+			fieldErrors["events"] = fmt.Sprintf("unknown event %q", event)
+			break
+		}
+
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	w.Events = events
+
+	secretLength := utf8.RuneCountInString(w.Secret)
+	if w.Secret != "" && (secretLength < minWebhookSecretLength || secretLength > maxWebhookSecretLength) {
+		fieldErrors["secret"] = fmt.Sprintf("must be between %d and %d characters long",
+			minWebhookSecretLength, maxWebhookSecretLength)
+	}
+
+	if len(fieldErrors) != 0 {
+		return errors.ErrInvalidWebhookFields(fieldErrors)
+	}
+
+	return nil
+}
+
+func (w WebhookProperties) ToProperties() models.WebhookProperties {
+	events := make([]models.PersonEventType, 0, len(w.Events))
+	for _, event := range w.Events {
+		events = append(events, models.PersonEventType(event))
+	}
+
+	return models.WebhookProperties{
+		URL:    w.URL,
+		Events: events,
+		Secret: w.Secret,
+	}
+}
+
+func parseWebhookDeliveriesQuery(ctx *fiber.Ctx, webhookID int) (models.WebhookDeliveriesQuery, error) {
+	fieldErrors := make(map[string]string)
+
+	query := models.WebhookDeliveriesQuery{
+		WebhookID: webhookID,
+		Status:    models.WebhookDeliveryStatus(ctx.Query("status")),
+		Offset:    parseNonNegativeInt(ctx, "offset", 0, fieldErrors),
+		Limit:     parseNonNegativeInt(ctx, "limit", defaultDeliveriesLimit, fieldErrors),
+	}
+
+	if query.Status != "" && !query.Status.Valid() {
+		fieldErrors["status"] = "must be one of " + strings.Join([]string{
+			string(models.DeliveryPending), string(models.DeliveryDelivered), string(models.DeliveryDead),
+		}, ", ")
+	}
+
+	if query.Limit > maxDeliveriesLimit {
+		fieldErrors["limit"] = "must not be greater than " + strconv.Itoa(maxDeliveriesLimit)
+	}
+
+	if len(fieldErrors) != 0 {
+		return models.WebhookDeliveriesQuery{}, errors.ErrInvalidDeliveriesQuery(fieldErrors)
+	}
+
+	return query, nil
+}
+
+func (d *webhookDelivery) GetWebhooks(ctx *fiber.Ctx) error {
+	webhooks, err := d.webhooks.GetWebhooks(ctx.UserContext())
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(NewWebhooksDTO(webhooks))
+}
+
+// PostWebhook subscribes the callback URL to the events. The response contains the secret of the signatures,
+// it is not returned by the other requests.
+func (d *webhookDelivery) PostWebhook(ctx *fiber.Ctx) error {
+	dto, err := decodeWebhookProperties(ctx.Body())
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	err = dto.Validate()
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	webhook, err := d.webhooks.CreateWebhook(ctx.UserContext(), dto.ToProperties())
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	ctx.Location(ctx.Path() + "/" + strconv.Itoa(webhook.ID))
+
+	return ctx.Status(fiber.StatusCreated).JSON(CreatedWebhook{
+		Webhook: NewWebhookDTO(webhook),
+		Secret:  webhook.Secret,
+	})
+}
+
+func (d *webhookDelivery) GetWebhook(ctx *fiber.Ctx) error {
+	webhookID, err := strconv.Atoi(ctx.Params("webhookId"))
+	if err != nil {
+		return problem.Send(ctx, errors.ErrInvalidWebhookID.Problem())
+	}
+
+	webhook, err := d.webhooks.GetWebhook(ctx.UserContext(), webhookID)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(NewWebhookDTO(webhook))
+}
+
+func (d *webhookDelivery) DeleteWebhook(ctx *fiber.Ctx) error {
+	webhookID, err := strconv.Atoi(ctx.Params("webhookId"))
+	if err != nil {
+		return problem.Send(ctx, errors.ErrInvalidWebhookID.Problem())
+	}
+
+	err = d.webhooks.DeleteWebhook(ctx.UserContext(), webhookID)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// GetWebhookDeliveries returns the delivery log of the webhook, the latest deliveries first.
+func (d *webhookDelivery) GetWebhookDeliveries(ctx *fiber.Ctx) error {
+	webhookID, err := strconv.Atoi(ctx.Params("webhookId"))
+	if err != nil {
+		return problem.Send(ctx, errors.ErrInvalidWebhookID.Problem())
+	}
+
+	query, err := parseWebhookDeliveriesQuery(ctx, webhookID)
+	if err != nil {
+		return d.sendValidationError(ctx, err)
+	}
+
+	page, err := d.webhooks.GetWebhookDeliveries(ctx.UserContext(), query)
+	if err != nil {
+		return d.sendError(ctx, err)
+	}
+
+	dto := NewWebhookDeliveriesPageDTO(page, query)
+	setOffsetPageLinks(ctx, query.Offset, query.Limit, dto.HasMore)
+
+	return ctx.Status(fiber.StatusOK).JSON(dto)
+}
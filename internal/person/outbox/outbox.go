@@ -0,0 +1,157 @@
+// Package outbox publishes the person events stored in the outbox by the transactions of the changes.
+// The relay publishes the events in the order of the outbox and marks them dispatched after the publisher
+// accepts them, so every event is published at least once.
+package outbox
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"log/slog"
+	"time"
+)
+
+// EventPublisher delivers the events to their consumers. The events are published again if it fails,
+// so the consumers must deduplicate them by the event IDs.
+type EventPublisher interface {
+	Publish(ctx context.Context, events []models.OutboxEvent) error
+}
+
+// MultiPublisher publishes the events to all publishers in turn. The events are published again to all of them
+// if any fails.
+type MultiPublisher []EventPublisher
+
+func (m MultiPublisher) Publish(ctx context.Context, events []models.OutboxEvent) error {
+	for _, publisher := range m {
+		err := publisher.Publish(ctx, events)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishFunc publishes the batch of the events claimed from the outbox.
+type PublishFunc func(ctx context.Context, events []models.OutboxEvent) error
+
+type Repository interface {
+	// DispatchOutboxEvents passes the oldest undispatched events to publish and marks them dispatched if it succeeds.
+	// The events are locked until they are marked, it returns the number of the dispatched events.
+	DispatchOutboxEvents(ctx context.Context, limit int, publish PublishFunc) (int, error)
+	// DeleteDispatchedOutboxEvents removes the events dispatched earlier than olderThan ago.
+	DeleteDispatchedOutboxEvents(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+// Reader reads the outbox without claiming the events, so every instance of the service may follow it.
+type Reader interface {
+	// GetOutboxEvents returns the events after the sequence in the order of the outbox, dispatched or not.
+	GetOutboxEvents(ctx context.Context, afterSequence int64, limit int) ([]models.OutboxEvent, error)
+	// GetOutboxBounds returns the sequence of the oldest event kept in the outbox and of the latest one.
+	// The oldest one follows the latest one if the outbox is empty.
+	GetOutboxBounds(ctx context.Context) (first, last int64, err error)
+}
+
+const (
+	defaultPollInterval    = time.Second
+	defaultBatchSize       = 100
+	defaultRetention       = 7 * 24 * time.Hour
+	defaultCleanupInterval = time.Hour
+)
+
+type Config struct {
+	PollInterval time.Duration `koanf:"poll_interval"`
+	BatchSize    int           `koanf:"batch_size"`
+	// Retention is the time the dispatched events are kept for, e.g. to be replayed to the change feed clients.
+	Retention time.Duration `koanf:"retention"`
+	// CleanupInterval is the interval of the removal of the dispatched events older than Retention.
+	CleanupInterval time.Duration   `koanf:"cleanup_interval"`
+	Publisher       PublisherConfig `koanf:"publisher"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+
+	if c.Retention <= 0 {
+		c.Retention = defaultRetention
+	}
+
+	if c.CleanupInterval <= 0 {
+		c.CleanupInterval = defaultCleanupInterval
+	}
+
+	return c
+}
+
+type Relay struct {
+	repo      Repository
+	publisher EventPublisher
+	config    Config
+	logger    *slog.Logger
+}
+
+func New(repo Repository, publisher EventPublisher, config Config, logger *slog.Logger) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		config:    config.withDefaults(),
+		logger:    logger,
+	}
+}
+
+// Run dispatches the outbox and removes the expired events periodically until the context is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(r.config.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Dispatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.logger.Error("failed to dispatch outbox events", slog.String("error", err.Error()))
+			}
+		case <-cleanup.C:
+			count, err := r.Cleanup(ctx)
+			if err != nil {
+				r.logger.Error("failed to delete dispatched outbox events", slog.String("error", err.Error()))
+				continue
+			}
+
+			r.logger.Info("dispatched outbox events deleted", slog.Int64("count", count))
+		}
+	}
+}
+
+// Cleanup removes the events dispatched earlier than the retention period ago.
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	return r.repo.DeleteDispatchedOutboxEvents(ctx, r.config.Retention)
+}
+
+// Dispatch publishes the undispatched events by batches until the outbox is drained.
+func (r *Relay) Dispatch(ctx context.Context) error {
+	for {
+		count, err := r.repo.DispatchOutboxEvents(ctx, r.config.BatchSize, r.publisher.Publish)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			r.logger.Debug("outbox events dispatched", slog.Int("count", count))
+		}
+
+		if count < r.config.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package outbox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/outbox"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type OutboxSuite struct {
+	suite.Suite
+}
+
+// age returns the pointer to the age of the person.
+func age(value int) *int {
+	return &value
+}
+
+func (*OutboxSuite) newEvent(sequence int64, eventType models.PersonEventType) models.OutboxEvent {
+	return models.OutboxEvent{
+		Sequence: sequence,
+		PersonEvent: models.PersonEvent{
+			ID:   "event-" + strconv.FormatInt(sequence, 10),
+			Type: eventType,
+			Person: models.Person{
+				ID:               int(sequence),
+				PersonProperties: models.PersonProperties{Name: "Aboba", Age: age(20)},
+				Version:          1,
+			},
+			OccurredAt: time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(context.Context, []models.OutboxEvent) error {
+	return errors.New("broker is unavailable")
+}
+
+func (s *OutboxSuite) TestDispatchDrainsOutbox(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	const batchSize = 2
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryMock)
+	repo.On("DispatchOutboxEvents", batchSize).Return([]models.OutboxEvent{
+		s.newEvent(1, models.PersonCreated),
+		s.newEvent(2, models.PersonUpdated),
+	}, nil).Once()
+	repo.On("DispatchOutboxEvents", batchSize).Return([]models.OutboxEvent{
+		s.newEvent(3, models.PersonDeleted),
+	}, nil).Once()
+	var buf bytes.Buffer
+	relay := outbox.New(repo, outbox.NewNDJSONPublisher(&buf), outbox.Config{BatchSize: batchSize}, logger)
+	// act
+	err := relay.Dispatch(context.Background())
+	// assert
+	t.Require().NoError(err)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "DispatchOutboxEvents", 2)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	t.Require().Len(lines, 3)
+	t.Require().Contains(lines[0], `"sequence":1`)
+	t.Require().Contains(lines[1], `"sequence":2`)
+	t.Require().Contains(lines[2], `"sequence":3`)
+}
+
+func (s *OutboxSuite) TestDispatchKeepsEventsOnPublishError(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryMock)
+	repo.On("DispatchOutboxEvents", 100).Return([]models.OutboxEvent{s.newEvent(1, models.PersonCreated)}, nil)
+	relay := outbox.New(repo, failingPublisher{}, outbox.Config{}, logger)
+	// act
+	err := relay.Dispatch(context.Background())
+	// assert
+	t.Require().Error(err)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "DispatchOutboxEvents", 1)
+}
+
+func (s *OutboxSuite) TestMultiPublisherStopsOnError(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryMock)
+	repo.On("DispatchOutboxEvents", 100).Return([]models.OutboxEvent{s.newEvent(1, models.PersonCreated)}, nil)
+	var buf bytes.Buffer
+	publishers := outbox.MultiPublisher{failingPublisher{}, outbox.NewNDJSONPublisher(&buf)}
+	relay := outbox.New(repo, publishers, outbox.Config{}, logger)
+	// act
+	err := relay.Dispatch(context.Background())
+	// assert
+	t.Require().Error(err)
+	repo.AssertExpectations(t)
+	t.Require().Empty(buf.String())
+}
+
+func (s *OutboxSuite) TestCleanup(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const deleted int64 = 3
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryMock)
+	repo.On("DeleteDispatchedOutboxEvents", 48*time.Hour).Return(deleted, nil)
+	relay := outbox.New(repo, outbox.MultiPublisher{}, outbox.Config{Retention: 48 * time.Hour}, logger)
+	// act
+	count, err := relay.Cleanup(context.Background())
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(deleted, count)
+	repo.AssertExpectations(t)
+}
+
+func (s *OutboxSuite) TestNDJSONPublisher(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	var buf bytes.Buffer
+	publisher := outbox.NewNDJSONPublisher(&buf)
+	event := s.newEvent(7, models.PersonUpdated)
+	// act
+	err := publisher.Publish(context.Background(), []models.OutboxEvent{event})
+	// assert
+	t.Require().NoError(err)
+	t.Require().JSONEq(`{"sequence":7,"id":"event-7","type":"person.updated","occurred_at":"2024-01-02T03:04:05Z",
+		"person_id":7,"person":{"id":7,"name":"Aboba","age":20,"address":"","work":"","version":1}}`, buf.String())
+	t.Require().True(strings.HasSuffix(buf.String(), "\n"))
+}
+
+func (s *OutboxSuite) TestFilePublisherAppends(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	path := t.TempDir() + "/outbox.ndjson"
+	publisher, err := outbox.OpenPublisher(outbox.PublisherConfig{Kind: outbox.PublisherFile, Path: path})
+	t.Require().NoError(err)
+	// act
+	firstErr := publisher.Publish(context.Background(), []models.OutboxEvent{s.newEvent(1, models.PersonCreated)})
+	secondErr := publisher.Publish(context.Background(), []models.OutboxEvent{s.newEvent(2, models.PersonDeleted)})
+	closeErr := publisher.Close()
+	// assert
+	t.Require().NoError(firstErr)
+	t.Require().NoError(secondErr)
+	t.Require().NoError(closeErr)
+	data, err := os.ReadFile(path)
+	t.Require().NoError(err)
+	t.Require().Equal(2, strings.Count(string(data), "\n"))
+}
+
+func (s *OutboxSuite) TestPublisherDefaultsToFile(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	path := t.TempDir() + "/outbox.ndjson"
+	// act
+	publisher, err := outbox.OpenPublisher(outbox.PublisherConfig{Path: path})
+	t.Require().NoError(err)
+	publishErr := publisher.Publish(context.Background(), []models.OutboxEvent{s.newEvent(1, models.PersonCreated)})
+	closeErr := publisher.Close()
+	// assert
+	t.Require().NoError(publishErr)
+	t.Require().NoError(closeErr)
+	data, err := os.ReadFile(path)
+	t.Require().NoError(err)
+	t.Require().Equal(1, strings.Count(string(data), "\n"))
+}
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(OutboxSuite))
+}
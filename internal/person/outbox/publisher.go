@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	PublisherStdout = "stdout"
+	PublisherFile   = "file"
+
+	defaultPublisherPath = "outbox.ndjson"
+)
+
+type PublisherConfig struct {
+	// Kind is the destination of the NDJSON events: file (default) or stdout. The stdout publisher
+	// is for local use only, the lines of the events are interleaved with the logs of the service.
+	Kind string `koanf:"kind"`
+	// Path is the file the events are appended to, outbox.ndjson by default.
+	Path string `koanf:"path"`
+}
+
+type personDTO struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Age       *int       `json:"age"`
+	Address   string     `json:"address"`
+	Work      string     `json:"work"`
+	Version   int64      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// eventDTO is the line of the NDJSON publisher.
+type eventDTO struct {
+	Sequence   int64     `json:"sequence"`
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	PersonID   int       `json:"person_id"`
+	Person     personDTO `json:"person"`
+}
+
+func newEventDTO(event models.OutboxEvent) eventDTO {
+	return eventDTO{
+		Sequence:   event.Sequence,
+		ID:         event.ID,
+		Type:       string(event.Type),
+		OccurredAt: event.OccurredAt.UTC(),
+		PersonID:   event.Person.ID,
+		Person: personDTO{
+			ID:        event.Person.ID,
+			Name:      event.Person.Name,
+			Age:       event.Person.Age,
+			Address:   event.Person.Address,
+			Work:      event.Person.Work,
+			Version:   event.Person.Version,
+			DeletedAt: event.Person.DeletedAt,
+		},
+	}
+}
+
+// NDJSONPublisher writes the events to the writer as JSON objects separated by newlines.
+type NDJSONPublisher struct {
+	mu   sync.Mutex
+	w    io.Writer
+	file *os.File
+}
+
+func NewNDJSONPublisher(w io.Writer) *NDJSONPublisher {
+	return &NDJSONPublisher{w: w}
+}
+
+// OpenFilePublisher returns the publisher appending the events to the file.
+func OpenFilePublisher(path string) (*NDJSONPublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "open outbox file")
+	}
+
+	return &NDJSONPublisher{w: file, file: file}, nil
+}
+
+// OpenPublisher returns the publisher of the config. It must be closed after the relay stops.
+func OpenPublisher(config PublisherConfig) (*NDJSONPublisher, error) {
+	switch config.Kind {
+	case PublisherFile, "":
+		if config.Path == "" {
+			config.Path = defaultPublisherPath
+		}
+
+		return OpenFilePublisher(config.Path)
+	case PublisherStdout:
+		return NewNDJSONPublisher(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", config.Kind)
+	}
+}
+
+// Publish writes the batch at once. The file is synced before the events are marked dispatched.
+func (p *NDJSONPublisher) Publish(_ context.Context, events []models.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w := bufio.NewWriter(p.w)
+	encoder := json.NewEncoder(w)
+
+	for _, event := range events {
+		err := encoder.Encode(newEventDTO(event))
+		if err != nil {
+			return errors.Wrap(err, "encode outbox event")
+		}
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return errors.Wrap(err, "write outbox events")
+	}
+
+	if p.file != nil {
+		return errors.Wrap(p.file.Sync(), "sync outbox file")
+	}
+
+	return nil
+}
+
+// Close closes the file of the publisher, the other writers are left open.
+func (p *NDJSONPublisher) Close() error {
+	if p.file == nil {
+		return nil
+	}
+
+	return p.file.Close()
+}
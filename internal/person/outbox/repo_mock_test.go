@@ -0,0 +1,35 @@
+package outbox_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/outbox"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+// RepositoryMock passes the events returned by the mock to the publisher like the outbox in the database.
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (r *RepositoryMock) DispatchOutboxEvents(ctx context.Context, limit int, publish outbox.PublishFunc) (int, error) {
+	args := r.Called(limit)
+
+	events := args.Get(0).([]models.OutboxEvent)
+	if len(events) == 0 || args.Error(1) != nil {
+		return 0, args.Error(1)
+	}
+
+	err := publish(ctx, events)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(events), nil
+}
+
+func (r *RepositoryMock) DeleteDispatchedOutboxEvents(_ context.Context, olderThan time.Duration) (int64, error) {
+	args := r.Called(olderThan)
+	return args.Get(0).(int64), args.Error(1)
+}
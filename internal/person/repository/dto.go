@@ -1,15 +1,23 @@
 package repository
 
-import "github.com/Inspirate789/ds-lab1/internal/models"
+import (
+	"encoding/json"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"time"
+)
 
 type Person struct {
 	ID int `sqlx:"id"`
 	PersonProperties
+	Version   int64      `db:"version"`
+	DeletedAt *time.Time `db:"deleted_at"`
 }
 
 type PersonProperties struct {
 	Name    string `sqlx:"name"`
-	Age     int    `sqlx:"age"`
+	Age     *int   `sqlx:"age"`
 	Address string `sqlx:"address"`
 	Work    string `sqlx:"work"`
 }
@@ -19,7 +27,7 @@ func (p Person) UpdateBy(properties PersonProperties) Person {
 		p.Name = properties.Name
 	}
 
-	if properties.Age != 0 {
+	if properties.Age != nil {
 		p.Age = properties.Age
 	}
 
@@ -43,6 +51,8 @@ func NewPerson(person models.Person) Person {
 			Address: person.Address,
 			Work:    person.Work,
 		},
+		Version:   person.Version,
+		DeletedAt: person.DeletedAt,
 	}
 }
 
@@ -64,6 +74,8 @@ func (p Person) ToModel() models.Person {
 			Address: p.Address,
 			Work:    p.Work,
 		},
+		Version:   p.Version,
+		DeletedAt: p.DeletedAt,
 	}
 }
 
@@ -78,3 +90,252 @@ func (p Persons) ToModel() []models.Person {
 
 	return dto
 }
+
+type IdempotencyKey struct {
+	Key         string `db:"key"`
+	Fingerprint string `db:"fingerprint"`
+	PersonID    int    `db:"person_id"`
+}
+
+// personSnapshot is stored in the history as JSON.
+type personSnapshot struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Age       *int       `json:"age"`
+	Address   string     `json:"address"`
+	Work      string     `json:"work"`
+	Version   int64      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+func newPersonSnapshot(person *Person) (*string, error) {
+	if person == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(personSnapshot{
+		ID:        person.ID,
+		Name:      person.Name,
+		Age:       person.Age,
+		Address:   person.Address,
+		Work:      person.Work,
+		Version:   person.Version,
+		DeletedAt: person.DeletedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := string(data)
+
+	return &snapshot, nil
+}
+
+func parsePersonSnapshot(data []byte) (*models.Person, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var snapshot personSnapshot
+
+	err := json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Person{
+		ID: snapshot.ID,
+		PersonProperties: models.PersonProperties{
+			Name:    snapshot.Name,
+			Age:     snapshot.Age,
+			Address: snapshot.Address,
+			Work:    snapshot.Work,
+		},
+		Version:   snapshot.Version,
+		DeletedAt: snapshot.DeletedAt,
+	}, nil
+}
+
+type PersonHistoryEntry struct {
+	ID        int64     `db:"id"`
+	PersonID  int       `db:"person_id"`
+	Version   int64     `db:"version"`
+	Operation string    `db:"operation"`
+	Before    []byte    `db:"before"`
+	After     []byte    `db:"after"`
+	Actor     string    `db:"actor"`
+	RequestID string    `db:"request_id"`
+	ChangedAt time.Time `db:"changed_at"`
+}
+
+func (e PersonHistoryEntry) ToModel() (models.PersonHistoryEntry, error) {
+	before, err := parsePersonSnapshot(e.Before)
+	if err != nil {
+		return models.PersonHistoryEntry{}, errors.Wrap(err, "parse person snapshot before change")
+	}
+
+	after, err := parsePersonSnapshot(e.After)
+	if err != nil {
+		return models.PersonHistoryEntry{}, errors.Wrap(err, "parse person snapshot after change")
+	}
+
+	return models.PersonHistoryEntry{
+		ID:        e.ID,
+		PersonID:  e.PersonID,
+		Version:   e.Version,
+		Operation: models.HistoryOperation(e.Operation),
+		Before:    before,
+		After:     after,
+		Actor:     e.Actor,
+		RequestID: e.RequestID,
+		ChangedAt: e.ChangedAt,
+	}, nil
+}
+
+type personHistoryRecord struct {
+	PersonID  int     `db:"person_id"`
+	Version   int64   `db:"version"`
+	Operation string  `db:"operation"`
+	Before    *string `db:"before"`
+	After     *string `db:"after"`
+	Actor     string  `db:"actor"`
+	RequestID string  `db:"request_id"`
+}
+
+type PersonSearchResult struct {
+	Person
+	Rank             float64 `db:"rank"`
+	NameHighlight    *string `db:"name_highlight"`
+	AddressHighlight *string `db:"address_highlight"`
+	WorkHighlight    *string `db:"work_highlight"`
+}
+
+func (r PersonSearchResult) ToModel() models.PersonSearchResult {
+	res := models.PersonSearchResult{
+		Person:     r.Person.ToModel(),
+		Rank:       r.Rank,
+		Highlights: make(map[models.PersonField]string),
+	}
+
+	for field, highlight := range map[models.PersonField]*string{
+		models.PersonFieldName:    r.NameHighlight,
+		models.PersonFieldAddress: r.AddressHighlight,
+		models.PersonFieldWork:    r.WorkHighlight,
+	} {
+		if highlight != nil {
+			res.Highlights[field] = *highlight
+		}
+	}
+
+	return res
+}
+
+type NameSuggestion struct {
+	Name       string  `db:"name"`
+	Similarity float64 `db:"similarity"`
+}
+
+type Webhook struct {
+	ID        int            `db:"id"`
+	URL       string         `db:"url"`
+	Events    pq.StringArray `db:"events"`
+	Secret    string         `db:"secret"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func (w Webhook) ToModel() models.Webhook {
+	events := make([]models.PersonEventType, 0, len(w.Events))
+	for _, event := range w.Events {
+		events = append(events, models.PersonEventType(event))
+	}
+
+	return models.Webhook{
+		ID: w.ID,
+		WebhookProperties: models.WebhookProperties{
+			URL:    w.URL,
+			Events: events,
+			Secret: w.Secret,
+		},
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+type WebhookDelivery struct {
+	ID            int64      `db:"id"`
+	WebhookID     int        `db:"webhook_id"`
+	EventID       string     `db:"event_id"`
+	EventType     string     `db:"event_type"`
+	Payload       []byte     `db:"payload"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     string     `db:"last_error"`
+	LastStatus    int        `db:"last_status"`
+	CreatedAt     time.Time  `db:"created_at"`
+	DeliveredAt   *time.Time `db:"delivered_at"`
+}
+
+func NewWebhookDelivery(delivery models.WebhookDelivery) WebhookDelivery {
+	return WebhookDelivery{
+		ID:            delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		EventID:       delivery.EventID,
+		EventType:     string(delivery.EventType),
+		Payload:       delivery.Payload,
+		Status:        string(delivery.Status),
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastError:     delivery.LastError,
+		LastStatus:    delivery.LastStatus,
+		CreatedAt:     delivery.CreatedAt,
+		DeliveredAt:   delivery.DeliveredAt,
+	}
+}
+
+func (d WebhookDelivery) ToModel() models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:            d.ID,
+		WebhookID:     d.WebhookID,
+		EventID:       d.EventID,
+		EventType:     models.PersonEventType(d.EventType),
+		Payload:       d.Payload,
+		Status:        models.WebhookDeliveryStatus(d.Status),
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		LastError:     d.LastError,
+		LastStatus:    d.LastStatus,
+		CreatedAt:     d.CreatedAt,
+		DeliveredAt:   d.DeliveredAt,
+	}
+}
+
+type WebhookDeliveryTask struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type OutboxEvent struct {
+	ID         int64     `db:"id"`
+	EventID    string    `db:"event_id"`
+	EventType  string    `db:"event_type"`
+	Person     string    `db:"person"`
+	OccurredAt time.Time `db:"occurred_at"`
+}
+
+func (e OutboxEvent) ToModel() (models.OutboxEvent, error) {
+	person, err := parsePersonSnapshot([]byte(e.Person))
+	if err != nil {
+		return models.OutboxEvent{}, errors.Wrap(err, "parse person snapshot of outbox event")
+	}
+
+	return models.OutboxEvent{
+		Sequence: e.ID,
+		PersonEvent: models.PersonEvent{
+			ID:         e.EventID,
+			Type:       models.PersonEventType(e.EventType),
+			Person:     *person,
+			OccurredAt: e.OccurredAt,
+		},
+	}, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"net"
+)
+
+// PostgreSQL error codes (https://www.postgresql.org/docs/current/errcodes-appendix.html).
+const (
+	connectionExceptionClass  pq.ErrorClass = "08"
+	insufficientResourceClass pq.ErrorClass = "53"
+	operatorInterventionClass pq.ErrorClass = "57"
+
+	uniqueViolation           pq.ErrorCode = "23505"
+	foreignKeyViolation       pq.ErrorCode = "23503"
+	notNullViolation          pq.ErrorCode = "23502"
+	checkViolation            pq.ErrorCode = "23514"
+	stringDataRightTruncation pq.ErrorCode = "22001"
+	numericValueOutOfRange    pq.ErrorCode = "22003"
+	serializationFailure      pq.ErrorCode = "40001"
+	deadlockDetected          pq.ErrorCode = "40P01"
+	queryCanceled             pq.ErrorCode = "57014"
+)
+
+func mapPQError(err error, pqErr *pq.Error) error {
+	switch pqErr.Code {
+	case uniqueViolation, foreignKeyViolation, serializationFailure, deadlockDetected:
+		return usecase.Conflict(err)
+	case notNullViolation, checkViolation, stringDataRightTruncation, numericValueOutOfRange:
+		return usecase.Validation(err)
+	case queryCanceled: // canceled by the context of the request, not by the server
+		return err
+	}
+
+	switch pqErr.Code.Class() {
+	case connectionExceptionClass, insufficientResourceClass, operatorInterventionClass:
+		return usecase.Unavailable(err)
+	default:
+		return err
+	}
+}
+
+// mapError converts the errors of the database into the errors of the use cases.
+// The missing person is reported as models.ErrPersonNotFound, errors of the use cases are returned as is.
+func mapError(err error) error {
+	if err == nil || usecase.KindOf(err) != usecase.KindInternal {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return usecase.NotFound(models.ErrPersonNotFound)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return mapPQError(err, pqErr)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return usecase.Unavailable(err)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return usecase.Unavailable(err)
+	}
+
+	return err
+}
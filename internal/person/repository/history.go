@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/audit"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+func newPersonHistoryRecord(
+	ctx context.Context,
+	operation models.HistoryOperation,
+	before, after *Person,
+) (personHistoryRecord, error) {
+	current := after
+	if current == nil {
+		current = before
+	}
+
+	beforeSnapshot, err := newPersonSnapshot(before)
+	if err != nil {
+		return personHistoryRecord{}, errors.Wrap(err, "encode person snapshot")
+	}
+
+	afterSnapshot, err := newPersonSnapshot(after)
+	if err != nil {
+		return personHistoryRecord{}, errors.Wrap(err, "encode person snapshot")
+	}
+
+	metadata := audit.FromContext(ctx)
+
+	return personHistoryRecord{
+		PersonID:  current.ID,
+		Version:   current.Version,
+		Operation: string(operation),
+		Before:    beforeSnapshot,
+		After:     afterSnapshot,
+		Actor:     metadata.Actor,
+		RequestID: metadata.RequestID,
+	}, nil
+}
+
+// insertHistoryTx stores the history records with one multi-row insert.
+func (r *sqlxRepository) insertHistoryTx(ctx context.Context, tx sqlx.ExtContext, records []personHistoryRecord) error {
+	namedQuery, args, err := sqlx.Named(insertPersonHistoryQuery, records)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, r.db.Rebind(namedQuery), args...)
+
+	return errors.Wrap(err, "record person history")
+}
+
+// recordHistoryTx stores the snapshots of the changed person with the audit metadata of the context
+// and the event of the change. It must be called in the transaction of the change.
+func (r *sqlxRepository) recordHistoryTx(
+	ctx context.Context,
+	tx *changeTx,
+	operation models.HistoryOperation,
+	before, after *Person,
+) error {
+	record, err := newPersonHistoryRecord(ctx, operation, before, after)
+	if err != nil {
+		return err
+	}
+
+	return r.recordChangesTx(ctx, tx, []personHistoryRecord{record})
+}
+
+func (r *sqlxRepository) GetPersonHistory(
+	ctx context.Context,
+	query models.PersonHistoryQuery,
+) (models.PersonHistoryPage, error) {
+	var entries []PersonHistoryEntry
+
+	err := r.db.SelectContext(ctx, &entries, selectPersonHistoryQuery, query.PersonID, query.Offset, query.Limit+1)
+	if err != nil {
+		return models.PersonHistoryPage{}, mapError(err)
+	}
+
+	page := models.PersonHistoryPage{
+		Entries: make([]models.PersonHistoryEntry, 0, len(entries)),
+	}
+
+	if int64(len(entries)) > query.Limit {
+		entries = entries[:query.Limit]
+		page.HasMore = true
+	}
+
+	for _, entry := range entries {
+		modelEntry, err := entry.ToModel()
+		if err != nil {
+			return models.PersonHistoryPage{}, err
+		}
+
+		page.Entries = append(page.Entries, modelEntry)
+	}
+
+	return page, nil
+}
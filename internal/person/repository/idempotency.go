@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// errIdempotencyKeyStored is returned if the key is stored by the concurrent request after it was looked up.
+var errIdempotencyKeyStored = errors.New("idempotency key was stored by a concurrent request")
+
+// getIdempotentResult returns the result stored with the live idempotency key. The returned flag is false
+// if the key is not stored or expired.
+func getIdempotentResult(ctx context.Context, db sqlx.QueryerContext, key models.IdempotencyKey) (models.IdempotentResult, bool, error) {
+	var stored IdempotencyKey
+
+	err := sqlx.GetContext(ctx, db, &stored, selectIdempotencyKeyQuery, key.Key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.IdempotentResult{}, false, nil
+	}
+
+	if err != nil {
+		return models.IdempotentResult{}, false, err
+	}
+
+	if stored.Fingerprint != key.Fingerprint {
+		return models.IdempotentResult{}, true, usecase.Conflict(models.ErrIdempotencyKeyReused)
+	}
+
+	return models.IdempotentResult{PersonID: stored.PersonID, Replayed: true}, true, nil
+}
+
+func (r *sqlxRepository) createPersonOnceTx(
+	ctx context.Context,
+	tx *changeTx,
+	key models.IdempotencyKey,
+	person models.PersonProperties,
+) (models.IdempotentResult, error) {
+	res, found, err := getIdempotentResult(ctx, tx, key)
+	if err != nil || found {
+		return res, err
+	}
+
+	identifiedPerson, err := r.createPersonTx(ctx, tx, NewPersonProperties(person))
+	if err != nil {
+		return models.IdempotentResult{}, err
+	}
+
+	stored, err := tx.ExecContext(ctx, insertIdempotencyKeyQuery, key.Key, key.Fingerprint, identifiedPerson.ID, key.TTL.Seconds())
+	if err != nil {
+		return models.IdempotentResult{}, errors.Wrap(err, "store idempotency key")
+	}
+
+	count, err := stored.RowsAffected()
+	if err != nil {
+		return models.IdempotentResult{}, errors.Wrap(err, "store idempotency key")
+	}
+
+	if count == 0 {
+		return models.IdempotentResult{}, usecase.Conflict(errIdempotencyKeyStored)
+	}
+
+	return models.IdempotentResult{PersonID: identifiedPerson.ID}, nil
+}
+
+// CreatePersonOnce creates the person and stores the idempotency key in the same transaction.
+// The repeated request with the same key gets the stored result until the key expires. If the concurrent
+// request with the same key wins, its result is returned as replayed once it is committed.
+func (r *sqlxRepository) CreatePersonOnce(
+	ctx context.Context,
+	key models.IdempotencyKey,
+	person models.PersonProperties,
+) (models.IdempotentResult, error) {
+	var res models.IdempotentResult
+	var err error
+
+	err = r.runChangeTx(ctx, func(tx *changeTx) error {
+		res, err = r.createPersonOnceTx(ctx, tx, key, person)
+		return err
+	})
+	if usecase.KindOf(err) != usecase.KindConflict || errors.Is(err, models.ErrIdempotencyKeyReused) {
+		return res, err
+	}
+
+	stored, found, lookupErr := getIdempotentResult(ctx, r.db, key)
+	if lookupErr != nil {
+		return models.IdempotentResult{}, mapError(lookupErr)
+	}
+
+	if !found {
+		return models.IdempotentResult{}, err
+	}
+
+	return stored, nil
+}
+
+// DeleteExpiredIdempotencyKeys removes the expired idempotency keys, which are not looked up anymore.
+func (r *sqlxRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, deleteExpiredIdempotencyKeysQuery)
+	if err != nil {
+		return 0, mapError(err)
+	}
+
+	return res.RowsAffected()
+}
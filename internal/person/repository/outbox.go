@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/outbox"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// NewSqlxOutboxRepository returns the outbox of the person events in the database of persons.
+func NewSqlxOutboxRepository(db *sqlx.DB, logger *slog.Logger) outbox.Repository {
+	return &sqlxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// NewSqlxOutboxReader returns the reader of the outbox of the person events in the database of persons.
+func NewSqlxOutboxReader(db *sqlx.DB, logger *slog.Logger) outbox.Reader {
+	return &sqlxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// outboxEventTypes are the types of the events of the recorded changes. The purged persons are already deleted,
+// so their purge is not an event.
+var outboxEventTypes = map[string]models.PersonEventType{
+	string(models.HistoryCreate):  models.PersonCreated,
+	string(models.HistoryUpdate):  models.PersonUpdated,
+	string(models.HistoryRestore): models.PersonUpdated,
+	string(models.HistoryDelete):  models.PersonDeleted,
+}
+
+// changeTx is the transaction of the changes of persons. The events of the changes are collected
+// and appended to the outbox at the end of the transaction.
+type changeTx struct {
+	sqlx.ExtContext
+	events []OutboxEvent
+}
+
+// runChangeTx runs the function in the serializable transaction and appends the events of its changes
+// to the outbox right before the commit.
+func (r *sqlxRepository) runChangeTx(ctx context.Context, f func(tx *changeTx) error) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		changes := &changeTx{ExtContext: tx}
+
+		err := f(changes)
+		if err != nil {
+			return err
+		}
+
+		return r.appendOutboxEventsTx(ctx, tx, changes.events)
+	})
+}
+
+// recordChangesTx stores the history records with one multi-row insert and collects the events
+// of the changes. The event contains the snapshot of the person after the change.
+func (r *sqlxRepository) recordChangesTx(ctx context.Context, tx *changeTx, records []personHistoryRecord) error {
+	err := r.insertHistoryTx(ctx, tx, records)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		eventType, ok := outboxEventTypes[record.Operation]
+		if !ok || record.After == nil {
+			continue
+		}
+
+		tx.events = append(tx.events, OutboxEvent{
+			EventID:   uuid.NewString(),
+			EventType: string(eventType),
+			Person:    *record.After,
+		})
+	}
+
+	return nil
+}
+
+// appendOutboxEventsTx locks the outbox until the commit and inserts the events by batches.
+// It is the last statement of the transaction, so the lock is held only for the inserts and the commit.
+func (r *sqlxRepository) appendOutboxEventsTx(ctx context.Context, tx sqlx.ExtContext, events []OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, lockOutboxQuery)
+	if err != nil {
+		return errors.Wrap(err, "lock outbox")
+	}
+
+	for batch := range slices.Chunk(events, insertBatchSize) {
+		namedQuery, args, err := sqlx.Named(insertOutboxEventQuery, batch)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, r.db.Rebind(namedQuery), args...)
+		if err != nil {
+			return errors.Wrap(err, "append outbox events")
+		}
+	}
+
+	return nil
+}
+
+// DispatchOutboxEvents runs in the read committed transaction, so the relay waiting for the events locked
+// by another one skips them once they are dispatched, and the relay does not fail the serializable changes.
+func (r *sqlxRepository) DispatchOutboxEvents(ctx context.Context, limit int, publish outbox.PublishFunc) (int, error) {
+	var count int
+
+	err := runTxWithIsolation(ctx, r.db, sql.LevelReadCommitted, func(tx *sqlx.Tx) error {
+		var events []OutboxEvent
+
+		err := tx.SelectContext(ctx, &events, selectOutboxEventsQuery, limit)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		modelEvents := make([]models.OutboxEvent, 0, len(events))
+		ids := make([]int64, 0, len(events))
+
+		for _, event := range events {
+			modelEvent, err := event.ToModel()
+			if err != nil {
+				return err
+			}
+
+			modelEvents = append(modelEvents, modelEvent)
+			ids = append(ids, event.ID)
+		}
+
+		err = publish(ctx, modelEvents)
+		if err != nil {
+			return errors.Wrap(err, "publish outbox events")
+		}
+
+		_, err = tx.ExecContext(ctx, markOutboxEventsQuery, pq.Int64Array(ids))
+		if err != nil {
+			return err
+		}
+
+		count = len(events)
+
+		return nil
+	})
+
+	return count, err
+}
+
+// DeleteDispatchedOutboxEvents removes the events dispatched earlier than olderThan ago.
+func (r *sqlxRepository) DeleteDispatchedOutboxEvents(ctx context.Context, olderThan time.Duration) (int64, error) {
+	res, err := r.db.ExecContext(ctx, deleteOutboxEventsQuery, olderThan.Seconds())
+	if err != nil {
+		return 0, errors.Wrap(err, "delete dispatched outbox events")
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *sqlxRepository) GetOutboxEvents(ctx context.Context, afterSequence int64, limit int) ([]models.OutboxEvent, error) {
+	var events []OutboxEvent
+
+	err := r.db.SelectContext(ctx, &events, selectOutboxEventsAfterQuery, afterSequence, limit)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	modelEvents := make([]models.OutboxEvent, 0, len(events))
+
+	for _, event := range events {
+		modelEvent, err := event.ToModel()
+		if err != nil {
+			return nil, err
+		}
+
+		modelEvents = append(modelEvents, modelEvent)
+	}
+
+	return modelEvents, nil
+}
+
+// GetOutboxBounds waits for the transactions appending to the outbox.
+func (r *sqlxRepository) GetOutboxBounds(ctx context.Context) (first, last int64, err error) {
+	var bounds struct {
+		First int64 `db:"first"`
+		Last  int64 `db:"last"`
+	}
+
+	// the appending transactions take the IDs under the lock, so the sequence is not ahead of the committed events
+	err = runTxWithIsolation(ctx, r.db, sql.LevelReadCommitted, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, lockOutboxQuery)
+		if err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &bounds, selectOutboxBoundsQuery)
+	})
+	if err != nil {
+		return 0, 0, mapError(err)
+	}
+
+	return bounds.First, bounds.Last, nil
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// unknownAgeSortValue places the persons without age before the others in the ascending order,
+// the keyset conditions do not match nulls.
+const unknownAgeSortValue = -1
+
+var sortColumns = map[models.PersonField]string{
+	models.PersonFieldID:      "id",
+	models.PersonFieldName:    "name",
+	models.PersonFieldAge:     "coalesce(age, " + strconv.Itoa(unknownAgeSortValue) + ")",
+	models.PersonFieldAddress: "address",
+	models.PersonFieldWork:    "work",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+func (b *queryBuilder) arg(value any) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *queryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+func (b *queryBuilder) filter(filter models.PersonsFilter) {
+	switch filter.Deleted {
+	case models.DeletedExclude:
+		b.where("deleted_at is null")
+	case models.DeletedOnly:
+		b.where("deleted_at is not null")
+	case models.DeletedInclude:
+	}
+
+	if filter.NameContains != "" {
+		b.where("name ilike " + b.arg("%"+likeEscaper.Replace(filter.NameContains)+"%"))
+	}
+
+	if filter.NamePrefix != "" {
+		b.where("name ilike " + b.arg(likeEscaper.Replace(filter.NamePrefix)+"%"))
+	}
+
+	if filter.Work != "" {
+		b.where("work = " + b.arg(filter.Work))
+	}
+
+	if filter.AgeMin != nil {
+		b.where("age >= " + b.arg(*filter.AgeMin))
+	}
+
+	if filter.AgeMax != nil {
+		b.where("age <= " + b.arg(*filter.AgeMax))
+	}
+
+	if filter.AddressContains != "" {
+		b.where("address ilike " + b.arg("%"+likeEscaper.Replace(filter.AddressContains)+"%"))
+	}
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+
+	return " where " + strings.Join(b.conditions, " and ")
+}
+
+// uniqueSortKeys removes unknown and repeated keys and always ends with the ID key
+// to make the order of persons deterministic.
+func uniqueSortKeys(keys []models.SortKey) []models.SortKey {
+	res := make([]models.SortKey, 0, len(keys)+1)
+	seen := make(map[models.PersonField]bool, len(keys)+1)
+
+	for _, key := range keys {
+		if _, ok := sortColumns[key.Field]; !ok || seen[key.Field] {
+			continue
+		}
+
+		res = append(res, key)
+		seen[key.Field] = true
+	}
+
+	if !seen[models.PersonFieldID] {
+		res = append(res, models.SortKey{Field: models.PersonFieldID})
+	}
+
+	return res
+}
+
+func sortValue(person models.Person, field models.PersonField) any {
+	value := person.FieldValue(field)
+	if value == nil {
+		return unknownAgeSortValue
+	}
+
+	return value
+}
+
+func orderByClause(keys []models.SortKey, reverse bool) string {
+	terms := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		column := sortColumns[key.Field]
+		if key.Descending != reverse {
+			column += " desc"
+		}
+
+		terms = append(terms, column)
+	}
+
+	return " order by " + strings.Join(terms, ", ")
+}
+
+// keyset adds the condition selecting persons after the cursor boundary in the order of keys:
+// (k1 > v1) or (k1 = v1 and k2 > v2) or ... (comparisons are inverted for descending keys and backward cursors).
+func (b *queryBuilder) keyset(keys []models.SortKey, cursor models.Cursor) {
+	alternatives := make([]string, 0, len(keys))
+
+	for i, key := range keys {
+		terms := make([]string, 0, i+1)
+
+		for _, prevKey := range keys[:i] {
+			terms = append(terms, sortColumns[prevKey.Field]+" = "+b.arg(sortValue(cursor.Boundary, prevKey.Field)))
+		}
+
+		operator := " > "
+		if key.Descending != cursor.Backward {
+			operator = " < "
+		}
+
+		terms = append(terms, sortColumns[key.Field]+operator+b.arg(sortValue(cursor.Boundary, key.Field)))
+		alternatives = append(alternatives, "("+strings.Join(terms, " and ")+")")
+	}
+
+	b.where("(" + strings.Join(alternatives, " or ") + ")")
+}
+
+// buildSelectPersonsQuery selects one more person than the limit to find out whether there are more persons.
+func buildSelectPersonsQuery(query models.PersonsQuery) (string, []any) {
+	var b queryBuilder
+
+	b.filter(query.Filter)
+
+	keys := uniqueSortKeys(query.Sort)
+	backward := false
+
+	if query.Keyset && query.Cursor != nil {
+		b.keyset(keys, *query.Cursor)
+		backward = query.Cursor.Backward
+	}
+
+	sqlQuery := selectPersonsQuery + b.whereClause() + orderByClause(keys, backward)
+
+	if query.Keyset {
+		query.Offset = 0
+	}
+
+	if query.Offset != 0 {
+		sqlQuery += " offset " + b.arg(query.Offset)
+	}
+
+	if query.Limit < math.MaxInt64 {
+		sqlQuery += " limit " + b.arg(query.Limit+1)
+	}
+
+	return sqlQuery, b.args
+}
+
+func buildCountPersonsQuery(filter models.PersonsFilter) (string, []any) {
+	var b queryBuilder
+
+	b.filter(filter)
+
+	return countPersonsQuery + b.whereClause(), b.args
+}
@@ -0,0 +1,182 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+)
+
+const selectPersons = `select id, name, age, address, work, version, deleted_at from persons`
+
+func (s *RepositorySuite) TestPersonsQuery(t provider.T) {
+	t.Epic("Persons query")
+	t.Severity(allure.CRITICAL)
+
+	boundary := models.Person{
+		ID:               7,
+		PersonProperties: models.PersonProperties{Name: "Aboba", Age: age(20), Address: "Moscow"},
+	}
+	unknownAge := models.Person{ID: 7, PersonProperties: models.PersonProperties{Name: "Aboba"}}
+
+	tests := []struct {
+		name  string
+		query models.PersonsQuery
+		sql   string
+		args  []driver.Value
+	}{
+		{
+			name:  "default order",
+			query: models.PersonsQuery{Limit: 10},
+			sql:   selectPersons + ` where deleted_at is null order by id limit $1`,
+			args:  []driver.Value{11},
+		},
+		{
+			name: "ascending key",
+			query: models.PersonsQuery{
+				Sort:  []models.SortKey{{Field: models.PersonFieldName}},
+				Limit: 10,
+			},
+			sql:  selectPersons + ` where deleted_at is null order by name, id limit $1`,
+			args: []driver.Value{11},
+		},
+		{
+			name: "descending key",
+			query: models.PersonsQuery{
+				Sort:  []models.SortKey{{Field: models.PersonFieldAge, Descending: true}},
+				Limit: 10,
+			},
+			sql:  selectPersons + ` where deleted_at is null order by coalesce(age, -1) desc, id limit $1`,
+			args: []driver.Value{11},
+		},
+		{
+			name: "ID tie-breaker is appended once",
+			query: models.PersonsQuery{
+				Sort: []models.SortKey{
+					{Field: models.PersonFieldID, Descending: true},
+					{Field: models.PersonFieldName},
+					{Field: models.PersonFieldID},
+				},
+				Limit: 10,
+			},
+			sql:  selectPersons + ` where deleted_at is null order by id desc, name limit $1`,
+			args: []driver.Value{11},
+		},
+		{
+			name: "repeated key is ignored",
+			query: models.PersonsQuery{
+				Sort:  []models.SortKey{{Field: models.PersonFieldWork}, {Field: models.PersonFieldWork, Descending: true}},
+				Limit: 10,
+			},
+			sql:  selectPersons + ` where deleted_at is null order by work, id limit $1`,
+			args: []driver.Value{11},
+		},
+		{
+			name:  "offset",
+			query: models.PersonsQuery{Offset: 20, Limit: 10},
+			sql:   selectPersons + ` where deleted_at is null order by id offset $1 limit $2`,
+			args:  []driver.Value{20, 11},
+		},
+		{
+			name:  "keyset ignores offset",
+			query: models.PersonsQuery{Offset: 20, Limit: 10, Keyset: true},
+			sql:   selectPersons + ` where deleted_at is null order by id limit $1`,
+			args:  []driver.Value{11},
+		},
+		{
+			name: "forward cursor",
+			query: models.PersonsQuery{
+				Sort:   []models.SortKey{{Field: models.PersonFieldName}},
+				Limit:  10,
+				Keyset: true,
+				Cursor: &models.Cursor{Boundary: boundary},
+			},
+			sql: selectPersons + ` where deleted_at is null and ((name > $1) or (name = $2 and id > $3))` +
+				` order by name, id limit $4`,
+			args: []driver.Value{"Aboba", "Aboba", 7, 11},
+		},
+		{
+			name: "forward cursor with descending key",
+			query: models.PersonsQuery{
+				Sort:   []models.SortKey{{Field: models.PersonFieldAddress, Descending: true}},
+				Limit:  10,
+				Keyset: true,
+				Cursor: &models.Cursor{Boundary: boundary},
+			},
+			sql: selectPersons + ` where deleted_at is null and ((address < $1) or (address = $2 and id > $3))` +
+				` order by address desc, id limit $4`,
+			args: []driver.Value{"Moscow", "Moscow", 7, 11},
+		},
+		{
+			name: "backward cursor",
+			query: models.PersonsQuery{
+				Sort:   []models.SortKey{{Field: models.PersonFieldAge, Descending: true}},
+				Limit:  10,
+				Keyset: true,
+				Cursor: &models.Cursor{Backward: true, Boundary: boundary},
+			},
+			sql: selectPersons + ` where deleted_at is null` +
+				` and ((coalesce(age, -1) > $1) or (coalesce(age, -1) = $2 and id < $3))` +
+				` order by coalesce(age, -1), id desc limit $4`,
+			args: []driver.Value{20, 20, 7, 11},
+		},
+		{
+			name: "cursor of person without age",
+			query: models.PersonsQuery{
+				Sort:   []models.SortKey{{Field: models.PersonFieldAge}},
+				Limit:  10,
+				Keyset: true,
+				Cursor: &models.Cursor{Boundary: unknownAge},
+			},
+			sql: selectPersons + ` where deleted_at is null` +
+				` and ((coalesce(age, -1) > $1) or (coalesce(age, -1) = $2 and id > $3))` +
+				` order by coalesce(age, -1), id limit $4`,
+			args: []driver.Value{-1, -1, 7, 11},
+		},
+		{
+			name: "filters",
+			query: models.PersonsQuery{
+				Filter: models.PersonsFilter{
+					NamePrefix: "Ab",
+					Work:       "IT",
+					AgeMin:     age(18),
+					AgeMax:     age(65),
+					Deleted:    models.DeletedOnly,
+				},
+				Limit: 10,
+			},
+			sql: selectPersons + ` where deleted_at is not null and name ilike $1 and work = $2` +
+				` and age >= $3 and age <= $4 order by id limit $5`,
+			args: []driver.Value{"Ab%", "IT", 18, 65, 11},
+		},
+		{
+			name: "patterns are escaped",
+			query: models.PersonsQuery{
+				Filter: models.PersonsFilter{
+					NameContains:    `50%_off`,
+					NamePrefix:      `C:\`,
+					AddressContains: `a_b%c\d`,
+					Deleted:         models.DeletedInclude,
+				},
+				Limit: 10,
+			},
+			sql:  selectPersons + ` where name ilike $1 and name ilike $2 and address ilike $3 order by id limit $4`,
+			args: []driver.Value{`%50\%\_off%`, `C:\\%`, `%a\_b\%c\\d%`, 11},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t provider.T) {
+			// arrange
+			repo, mock := s.newRepository(t, sqlmock.QueryMatcherEqual)
+			mock.ExpectQuery(test.sql).WithArgs(test.args...).WillReturnRows(sqlmock.NewRows(personColumns))
+			// act
+			_, err := repo.GetPersons(context.Background(), test.query)
+			// assert
+			t.Require().NoError(err)
+			t.Require().NoError(mock.ExpectationsWereMet())
+		})
+	}
+}
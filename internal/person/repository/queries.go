@@ -1,9 +1,104 @@
 package repository
 
+// personColumns are selected instead of * to skip the columns that are used only inside the database.
+const personColumns = `id, name, age, address, work, version, deleted_at`
+
+const (
+	selectPersonsQuery      = `select ` + personColumns + ` from persons`
+	countPersonsQuery       = `select count(*) from persons`
+	insertPersonQuery       = `insert into persons(name, age, address, work) values (:name, :age, :address, :work) returning ` + personColumns + `;`
+	selectPersonQuery       = `select ` + personColumns + ` from persons where id=$1 and deleted_at is null limit 1;`
+	updatePersonQuery       = `update persons set name=:name, age=:age, address=:address, work=:work, version=version+1 where id=:id returning ` + personColumns + `;`
+	insertPersonWithIDQuery = `insert into persons(id, name, age, address, work) overriding system value values (:id, :name, :age, :address, :work)
+		on conflict (id) do nothing returning ` + personColumns + `;`
+	syncPersonIDQuery        = `select setval(pg_get_serial_sequence('persons', 'id'), greatest($1, coalesce(pg_sequence_last_value(pg_get_serial_sequence('persons', 'id')), 1)));`
+	deletePersonQuery        = `update persons set deleted_at=now(), version=version+1 where id=$1 and deleted_at is null returning ` + personColumns + `;`
+	selectDeletedPersonQuery = `select ` + personColumns + ` from persons where id=$1 and deleted_at is not null limit 1;`
+	restorePersonQuery       = `update persons set deleted_at=null, version=version+1 where id=$1 and deleted_at is not null returning ` + personColumns + `;`
+	purgePersonsQuery        = `with purged as (delete from persons where deleted_at < now() - $1 * interval '1 second' returning ` + personColumns + `)
+		insert into person_history(person_id, version, operation, before, actor, request_id)
+		select id, version, 'purge', jsonb_build_object('id', id, 'name', name, 'age', age, 'address', address, 'work', work, 'version', version, 'deleted_at', deleted_at), $2, $3
+		from purged;`
+)
+
+const (
+	deleteExpiredIdempotencyKeysQuery = `delete from idempotency_keys where expires_at <= now();`
+	selectIdempotencyKeyQuery         = `select key, fingerprint, person_id from idempotency_keys where key=$1 and expires_at > now();`
+	// insertIdempotencyKeyQuery replaces the expired key, which is not deleted yet. The live key is kept.
+	insertIdempotencyKeyQuery = `insert into idempotency_keys(key, fingerprint, person_id, expires_at) values ($1, $2, $3, now() + $4 * interval '1 second')
+		on conflict (key) do update set fingerprint=excluded.fingerprint, person_id=excluded.person_id, created_at=now(), expires_at=excluded.expires_at
+		where idempotency_keys.expires_at <= now();`
+)
+
+const (
+	insertPersonHistoryQuery = `insert into person_history(person_id, version, operation, before, after, actor, request_id)
+		values (:person_id, :version, :operation, :before, :after, :actor, :request_id);`
+	selectPersonHistoryQuery = `select * from person_history where person_id=$1 order by id desc offset $2 limit $3;`
+)
+
+// searchPersonsQuery ranks the persons matching the web search query. Highlights are null for unmatched fields.
+// The name, address and work are HTML-escaped before ts_headline, so the marks are the only HTML of the highlights.
+// The parser reads the escaped characters as entities, which are not words, so the same words are marked.
+const searchPersonsQuery = `select ` + personColumns + `, ts_rank(search, query) as rank,
+		case when to_tsvector('simple', name) @@ query then ts_headline('simple', escaped.fields[1], query, $4) end as name_highlight,
+		case when to_tsvector('simple', address) @@ query then ts_headline('simple', escaped.fields[2], query, $4) end as address_highlight,
+		case when to_tsvector('simple', work) @@ query then ts_headline('simple', escaped.fields[3], query, $4) end as work_highlight
+	from persons, websearch_to_tsquery('simple', $1) as query,
+		lateral (select array_agg(replace(replace(replace(replace(replace(field, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')
+			order by i) as fields from unnest(array[persons.name, persons.address, persons.work]) with ordinality as f(field, i)) as escaped
+	where search @@ query and deleted_at is null
+	order by rank desc, id offset $2 limit $3;`
+
+const (
+	setSimilarityThresholdQuery = `select set_config('pg_trgm.similarity_threshold', $1, true);`
+	suggestNamesQuery           = `select name, similarity(name, $1) as similarity from persons
+		where name % $1 and deleted_at is null
+		group by name order by similarity desc, name limit $2;`
+	suggestNamesByPrefixQuery = `select name, similarity(name, $1) as similarity from persons
+		where lower(name) like lower($2) and deleted_at is null
+		group by name order by similarity desc, name limit $3;`
+)
+
+const (
+	insertWebhookQuery  = `insert into webhooks(url, events, secret) values ($1, $2, $3) returning *;`
+	selectWebhooksQuery = `select * from webhooks order by id;`
+	selectWebhookQuery  = `select * from webhooks where id=$1;`
+	deleteWebhookQuery  = `delete from webhooks where id=$1;`
+	selectDeliveryQuery = `select * from webhook_deliveries where webhook_id=$1 and ($2 = '' or status=$2) order by id desc offset $3 limit $4;`
+	// enqueueDeliveryQuery skips the events enqueued already by the unique index webhook_deliveries_event_idx,
+	// the relay republishes the events of the batch that failed to be marked dispatched.
+	enqueueDeliveryQuery = `insert into webhook_deliveries(webhook_id, event_id, event_type, payload)
+		select w.id, e.id, e.type, e.payload::jsonb
+		from unnest($1::text[], $2::text[], $3::text[]) with ordinality as e(id, type, payload, n)
+		join webhooks w on e.type = any(w.events)
+		order by e.n, w.id
+		on conflict (webhook_id, event_id) do nothing;`
+	// claimDeliveriesQuery postpones the due deliveries by the lease, the locked ones are being claimed by others.
+	claimDeliveriesQuery = `update webhook_deliveries as d set next_attempt_at = now() + $2 * interval '1 second'
+		from webhooks as w
+		where w.id = d.webhook_id and d.id in (
+			select id from webhook_deliveries where status = 'pending' and next_attempt_at <= now()
+			order by next_attempt_at, id limit $1 for update skip locked
+		)
+		returning d.*, w.url, w.secret;`
+	updateDeliveryQuery = `update webhook_deliveries set status=:status, attempts=:attempts, next_attempt_at=:next_attempt_at,
+		last_error=:last_error, last_status=:last_status, delivered_at=:delivered_at where id=:id;`
+)
+
 const (
-	selectPersonsQuery = `select * from persons offset $1 limit $2;`
-	insertPersonQuery  = `insert into persons(name, age, address, work) values (:name, :age, :address, :work) returning *;`
-	selectPersonQuery  = `select * from persons where id=$1 limit 1;`
-	updatePersonQuery  = `update persons set name=:name, age=:age, address=:address, work=:work where id=:id returning *;`
-	deletePersonQuery  = `delete from persons where id=$1;`
+	// lockOutboxQuery serializes the transactions appending to the outbox until they commit, so the IDs
+	// of the events are assigned in the order of the commits and the readers following the IDs skip nothing.
+	// The price is that the commits of the changes of persons are serialized across all instances,
+	// so the lock is taken by the last statements of the transaction only, after all changes are made.
+	lockOutboxQuery        = `select pg_advisory_xact_lock(hashtext('outbox'));`
+	insertOutboxEventQuery = `insert into outbox(event_id, event_type, person) values (:event_id, :event_type, :person);`
+	// selectOutboxEventsQuery locks the events until they are dispatched, the concurrent relays wait for them
+	// instead of skipping them to keep the order of the events.
+	selectOutboxEventsQuery      = `select id, event_id, event_type, person, occurred_at from outbox where dispatched_at is null order by id limit $1 for update;`
+	markOutboxEventsQuery        = `update outbox set dispatched_at=now() where id = any($1);`
+	deleteOutboxEventsQuery      = `delete from outbox where dispatched_at < now() - $1 * interval '1 second';`
+	selectOutboxEventsAfterQuery = `select id, event_id, event_type, person, occurred_at from outbox where id > $1 order by id limit $2;`
+	// selectOutboxBoundsQuery returns the next value of the sequence as the first ID if all events are removed.
+	selectOutboxBoundsQuery = `select coalesce(min(id), pg_sequence_last_value(pg_get_serial_sequence('outbox', 'id')::regclass) + 1, 1) as first,
+       coalesce(max(id), pg_sequence_last_value(pg_get_serial_sequence('outbox', 'id')::regclass), 0) as last from outbox;`
 )
@@ -1,14 +1,19 @@
 package repository
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"github.com/Inspirate789/ds-lab1/internal/models"
 	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/audit"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	"go.uber.org/multierr"
+	"iter"
 	"log/slog"
+	"slices"
+	"time"
 )
 
 type sqlxRepository struct {
@@ -24,45 +29,129 @@ func NewSqlxRepository(db *sqlx.DB, logger *slog.Logger) usecase.Repository {
 }
 
 func (r *sqlxRepository) HealthCheck(_ context.Context) error {
-	return r.db.Ping()
+	return mapError(r.db.Ping())
 }
 
-func (r *sqlxRepository) GetPersons(ctx context.Context, offset, limit int64) ([]models.Person, error) {
+func (r *sqlxRepository) GetPersons(ctx context.Context, query models.PersonsQuery) (models.PersonsPage, error) {
 	var persons Persons
 
-	err := r.db.SelectContext(ctx, &persons, selectPersonsQuery, offset, limit)
+	sqlQuery, args := buildSelectPersonsQuery(query)
+
+	err := r.db.SelectContext(ctx, &persons, sqlQuery, args...)
 	if errors.Is(err, sql.ErrNoRows) {
-		return make([]models.Person, 0), nil
+		return models.PersonsPage{Persons: make([]models.Person, 0)}, nil
+	}
+
+	if err != nil {
+		return models.PersonsPage{}, mapError(err)
+	}
+
+	hasMore := query.Limit >= 0 && int64(len(persons)) > query.Limit
+	if hasMore {
+		persons = persons[:query.Limit]
+	}
+
+	if query.Keyset && query.Cursor != nil && query.Cursor.Backward {
+		slices.Reverse(persons)
 	}
 
-	return persons.ToModel(), err
+	return models.PersonsPage{
+		Persons: persons.ToModel(),
+		HasMore: hasMore,
+	}, nil
 }
 
-func (r *sqlxRepository) CreatePerson(ctx context.Context, person models.PersonProperties) (models.Person, error) {
-	properties := NewPersonProperties(person)
+// IteratePersons returns the iterator over persons matching the query. Persons are read from the database
+// while the iterator is consumed and the rows are closed when the iteration stops. Cancel the context
+// before stopping the iteration early, so that the rest of the rows are not read on close.
+func (r *sqlxRepository) IteratePersons(ctx context.Context, query models.PersonsQuery) iter.Seq2[models.Person, error] {
+	return func(yield func(models.Person, error) bool) {
+		sqlQuery, args := buildSelectPersonsQuery(query)
+
+		rows, err := r.db.QueryxContext(ctx, sqlQuery, args...)
+		if err != nil {
+			yield(models.Person{}, mapError(err))
+			return
+		}
+
+		defer func() {
+			_ = rows.Close()
+		}()
+
+		// the query selects one more person than the limit
+		for count := int64(0); count < query.Limit && rows.Next(); count++ {
+			var person Person
 
+			err = rows.StructScan(&person)
+			if err != nil {
+				yield(models.Person{}, err)
+				return
+			}
+
+			if !yield(person.ToModel(), nil) {
+				return
+			}
+		}
+
+		err = rows.Err()
+		if err != nil {
+			yield(models.Person{}, mapError(err))
+		}
+	}
+}
+
+func (r *sqlxRepository) CountPersons(ctx context.Context, filter models.PersonsFilter) (int64, error) {
+	var count int64
+
+	sqlQuery, args := buildCountPersonsQuery(filter)
+
+	err := r.db.GetContext(ctx, &count, sqlQuery, args...)
+
+	return count, mapError(err)
+}
+
+func (r *sqlxRepository) createPersonTx(ctx context.Context, tx *changeTx, properties PersonProperties) (Person, error) {
 	namedQuery, args, err := sqlx.Named(insertPersonQuery, &properties)
 	if err != nil {
-		return models.Person{}, err
+		return Person{}, err
 	}
 
 	var identifiedPerson Person
 
-	err = r.db.GetContext(ctx, &identifiedPerson, r.db.Rebind(namedQuery), args...)
+	err = sqlx.GetContext(ctx, tx, &identifiedPerson, r.db.Rebind(namedQuery), args...)
+	if err != nil {
+		return Person{}, err
+	}
+
+	err = r.recordHistoryTx(ctx, tx, models.HistoryCreate, nil, &identifiedPerson)
+	if err != nil {
+		return Person{}, err
+	}
+
+	return identifiedPerson, nil
+}
+
+func (r *sqlxRepository) CreatePerson(ctx context.Context, person models.PersonProperties) (models.Person, error) {
+	var identifiedPerson Person
+	var err error
+
+	err = r.runChangeTx(ctx, func(tx *changeTx) error {
+		identifiedPerson, err = r.createPersonTx(ctx, tx, NewPersonProperties(person))
+		return err
+	})
 
 	return identifiedPerson.ToModel(), err
 }
 
-func (r *sqlxRepository) GetPerson(ctx context.Context, personID int) (models.Person, bool, error) {
+func (r *sqlxRepository) GetPerson(ctx context.Context, personID int) (models.Person, error) {
 	var identifiedPerson Person
 
 	err := r.db.GetContext(ctx, &identifiedPerson, selectPersonQuery, personID)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return models.Person{}, false, nil
+	if err != nil {
+		return models.Person{}, mapError(err)
 	}
 
-	return identifiedPerson.ToModel(), true, err
+	return identifiedPerson.ToModel(), nil
 }
 
 type txFunc func(tx *sqlx.Tx) error
@@ -71,16 +160,22 @@ type txRunner interface {
 	BeginTxx(context.Context, *sql.TxOptions) (*sqlx.Tx, error)
 }
 
-func runTx(ctx context.Context, db txRunner, f txFunc) (err error) {
+// runTx runs the function in the serializable transaction. The errors of the database, including
+// the serialization failure on commit, are mapped onto the errors of the use cases.
+func runTx(ctx context.Context, db txRunner, f txFunc) error {
+	return runTxWithIsolation(ctx, db, sql.LevelSerializable, f)
+}
+
+func runTxWithIsolation(ctx context.Context, db txRunner, isolation sql.IsolationLevel, f txFunc) (err error) {
 	var tx *sqlx.Tx
 
 	opts := &sql.TxOptions{
-		Isolation: sql.LevelSerializable,
+		Isolation: isolation,
 	}
 
 	tx, err = db.BeginTxx(ctx, opts)
 	if err != nil {
-		return errors.Wrap(err, "begin transaction")
+		return mapError(errors.Wrap(err, "begin transaction"))
 	}
 
 	defer func() {
@@ -89,20 +184,48 @@ func runTx(ctx context.Context, db txRunner, f txFunc) (err error) {
 		} else {
 			err = tx.Commit()
 		}
+
+		err = mapError(err)
 	}()
 
 	return f(tx)
 }
 
-func (r *sqlxRepository) updatePersonTx(ctx context.Context, tx sqlx.ExtContext, person Person) (Person, error) {
+type updateFunc func(person Person) (Person, error)
+
+func checkVersion(person Person, version int64) error {
+	if version != models.AnyVersion && person.Version != version {
+		return usecase.Conflict(models.ErrVersionMismatch)
+	}
+
+	return nil
+}
+
+func (r *sqlxRepository) updatePersonTx(
+	ctx context.Context,
+	tx *changeTx,
+	personID int,
+	version int64,
+	update updateFunc,
+) (Person, error) {
 	var res Person
 
-	err := sqlx.GetContext(ctx, tx, &res, selectPersonQuery, person.ID)
+	err := sqlx.GetContext(ctx, tx, &res, selectPersonQuery, personID)
 	if err != nil {
 		return Person{}, err
 	}
 
-	res = res.UpdateBy(person.PersonProperties)
+	err = checkVersion(res, version)
+	if err != nil {
+		return Person{}, err
+	}
+
+	before := res
+
+	res, err = update(res)
+	if err != nil {
+		return Person{}, err
+	}
 
 	namedQuery, args, err := sqlx.Named(updatePersonQuery, &res)
 	if err != nil {
@@ -114,39 +237,339 @@ func (r *sqlxRepository) updatePersonTx(ctx context.Context, tx sqlx.ExtContext,
 		return Person{}, err
 	}
 
+	err = r.recordHistoryTx(ctx, tx, models.HistoryUpdate, &before, &res)
+	if err != nil {
+		return Person{}, err
+	}
+
 	return res, nil
 }
 
-func (r *sqlxRepository) UpdatePerson(ctx context.Context, person models.Person) (models.Person, bool, error) {
+func (r *sqlxRepository) UpdatePerson(ctx context.Context, person models.Person, version int64) (models.Person, error) {
 	var res Person
 	var err error
 
-	err = runTx(ctx, r.db, func(tx *sqlx.Tx) error {
-		res, err = r.updatePersonTx(ctx, tx, NewPerson(person))
+	properties := NewPersonProperties(person.PersonProperties)
+
+	err = r.runChangeTx(ctx, func(tx *changeTx) error {
+		res, err = r.updatePersonTx(ctx, tx, person.ID, version, func(person Person) (Person, error) {
+			return person.UpdateBy(properties), nil
+		})
 		return err
 	})
 
 	if err != nil {
-		return models.Person{}, false, err
+		return models.Person{}, err
+	}
+
+	return res.ToModel(), nil
+}
+
+// PatchPerson applies the patch to the current state of the person in the same transaction as the update.
+func (r *sqlxRepository) PatchPerson(
+	ctx context.Context,
+	personID int,
+	patch models.PersonPatch,
+	version int64,
+) (models.Person, error) {
+	var res Person
+	var err error
+
+	err = r.runChangeTx(ctx, func(tx *changeTx) error {
+		res, err = r.updatePersonTx(ctx, tx, personID, version, func(person Person) (Person, error) {
+			patched, err := patch.Apply(person.ToModel())
+			return NewPerson(patched), err
+		})
+		return err
+	})
+	if err != nil {
+		return models.Person{}, err
 	}
 
+	return res.ToModel(), nil
+}
+
+func (r *sqlxRepository) createPersonWithIDTx(ctx context.Context, tx *changeTx, person Person) (Person, error) {
+	var res Person
+
+	namedQuery, args, err := sqlx.Named(insertPersonWithIDQuery, &person)
+	if err != nil {
+		return Person{}, err
+	}
+
+	// the ID is taken only by the deleted person here, as the update did not find the person,
+	// and it must be restored to keep its history
+	err = sqlx.GetContext(ctx, tx, &res, r.db.Rebind(namedQuery), args...)
 	if errors.Is(err, sql.ErrNoRows) {
-		return models.Person{}, false, nil
+		return Person{}, usecase.Conflict(models.ErrPersonDeleted)
 	}
 
-	return res.ToModel(), true, nil
+	if err != nil {
+		return Person{}, err
+	}
+
+	// the identity sequence must not generate the ID chosen by the client later
+	_, err = tx.ExecContext(ctx, syncPersonIDQuery, res.ID)
+	if err != nil {
+		return Person{}, err
+	}
+
+	err = r.recordHistoryTx(ctx, tx, models.HistoryCreate, nil, &res)
+	if err != nil {
+		return Person{}, err
+	}
+
+	return res, nil
 }
 
-func (r *sqlxRepository) DeletePerson(ctx context.Context, personID int) (bool, error) {
-	_, err := r.db.ExecContext(ctx, deletePersonQuery, personID)
+func (r *sqlxRepository) replacePersonTx(
+	ctx context.Context,
+	tx *changeTx,
+	person Person,
+	version int64,
+	createIfAbsent bool,
+) (Person, bool, error) {
+	res, err := r.updatePersonTx(ctx, tx, person.ID, version, func(Person) (Person, error) {
+		return person, nil
+	})
+	if err == nil {
+		return res, false, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) || !createIfAbsent {
+		return Person{}, false, err
+	}
 
+	res, err = r.createPersonWithIDTx(ctx, tx, person)
+	if err != nil {
+		return Person{}, false, err
+	}
+
+	return res, true, nil
+}
+
+// ReplacePerson replaces all properties of the existing person. If the person does not exist,
+// it is created with the given ID when createIfAbsent is set. The returned flag reports whether the person was created.
+func (r *sqlxRepository) ReplacePerson(
+	ctx context.Context,
+	person models.Person,
+	version int64,
+	createIfAbsent bool,
+) (models.Person, bool, error) {
+	var res Person
+	var created bool
+	var err error
+
+	err = r.runChangeTx(ctx, func(tx *changeTx) error {
+		res, created, err = r.replacePersonTx(ctx, tx, NewPerson(person), version, createIfAbsent)
+		return err
+	})
+	if err != nil {
+		return models.Person{}, false, err
+	}
+
+	return res.ToModel(), created, nil
+}
+
+func (r *sqlxRepository) deletePersonTx(ctx context.Context, tx *changeTx, personID int, version int64) error {
+	var person Person
+
+	err := sqlx.GetContext(ctx, tx, &person, selectPersonQuery, personID)
+	if err != nil {
+		return err
+	}
+
+	err = checkVersion(person, version)
+	if err != nil {
+		return err
+	}
+
+	var deleted Person
+
+	err = sqlx.GetContext(ctx, tx, &deleted, deletePersonQuery, personID)
+	if err != nil {
+		return err
+	}
+
+	return r.recordHistoryTx(ctx, tx, models.HistoryDelete, &person, &deleted)
+}
+
+func (r *sqlxRepository) DeletePerson(ctx context.Context, personID int, version int64) error {
+	return r.runChangeTx(ctx, func(tx *changeTx) error {
+		return r.deletePersonTx(ctx, tx, personID, version)
+	})
+}
+
+func (r *sqlxRepository) restorePersonTx(ctx context.Context, tx *changeTx, personID int) (Person, error) {
+	var before, res Person
+
+	err := sqlx.GetContext(ctx, tx, &before, selectDeletedPersonQuery, personID)
 	if errors.Is(err, sql.ErrNoRows) {
-		return false, nil
+		return Person{}, usecase.NotFound(models.ErrDeletedPersonNotFound)
+	}
+
+	if err != nil {
+		return Person{}, err
+	}
+
+	err = sqlx.GetContext(ctx, tx, &res, restorePersonQuery, personID)
+	if err != nil {
+		return Person{}, err
+	}
+
+	err = r.recordHistoryTx(ctx, tx, models.HistoryRestore, &before, &res)
+	if err != nil {
+		return Person{}, err
+	}
+
+	return res, nil
+}
+
+// RestorePerson moves the person out of the trash. models.ErrDeletedPersonNotFound is returned
+// if there is no such deleted person.
+func (r *sqlxRepository) RestorePerson(ctx context.Context, personID int) (models.Person, error) {
+	var res Person
+	var err error
+
+	err = r.runChangeTx(ctx, func(tx *changeTx) error {
+		res, err = r.restorePersonTx(ctx, tx, personID)
+		return err
+	})
+	if err != nil {
+		return models.Person{}, err
+	}
+
+	return res.ToModel(), nil
+}
+
+// PurgeDeletedPersons permanently removes persons deleted earlier than olderThan ago.
+// The snapshots of purged persons are recorded into the history by the same statement.
+func (r *sqlxRepository) PurgeDeletedPersons(ctx context.Context, olderThan time.Duration) (int64, error) {
+	metadata := audit.FromContext(ctx)
+
+	res, err := r.db.ExecContext(ctx, purgePersonsQuery, olderThan.Seconds(), metadata.Actor, metadata.RequestID)
+	if err != nil {
+		return 0, mapError(err)
+	}
+
+	return res.RowsAffected()
+}
+
+// insertBatchSize limits the number of rows inserted by one statement to stay within the limit of query parameters.
+const insertBatchSize = 1000
+
+func (r *sqlxRepository) createPersonsTx(
+	ctx context.Context,
+	tx *changeTx,
+	persons []models.PersonProperties,
+) (Persons, error) {
+	properties := make([]PersonProperties, 0, len(persons))
+	for _, person := range persons {
+		properties = append(properties, NewPersonProperties(person))
+	}
+
+	namedQuery, args, err := sqlx.Named(insertPersonQuery, properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var created Persons
+
+	err = sqlx.SelectContext(ctx, tx, &created, r.db.Rebind(namedQuery), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs are generated in the order of values, so the order of persons is restored by them
+	slices.SortFunc(created, func(a, b Person) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	records := make([]personHistoryRecord, 0, len(created))
+
+	for i := range created {
+		record, err := newPersonHistoryRecord(ctx, models.HistoryCreate, nil, &created[i])
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
 	}
 
+	err = r.recordChangesTx(ctx, tx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// CreatePersons creates all persons in one transaction with multi-row inserts.
+// The created persons are returned in the order of the given ones.
+func (r *sqlxRepository) CreatePersons(ctx context.Context, persons []models.PersonProperties) ([]models.Person, error) {
+	res := make(Persons, 0, len(persons))
+
+	err := r.runChangeTx(ctx, func(tx *changeTx) error {
+		for batch := range slices.Chunk(persons, insertBatchSize) {
+			created, err := r.createPersonsTx(ctx, tx, batch)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, created...)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return true, nil
+	return res.ToModel(), nil
+}
+
+func (r *sqlxRepository) applyBatchOperationTx(
+	ctx context.Context,
+	tx *changeTx,
+	operation models.BatchOperation,
+	personID int,
+) (Person, error) {
+	switch operation.Type {
+	case models.BatchCreate:
+		return r.createPersonTx(ctx, tx, NewPersonProperties(operation.Person))
+	case models.BatchPatch:
+		return r.updatePersonTx(ctx, tx, personID, operation.Version, func(person Person) (Person, error) {
+			patched, err := operation.Patch.Apply(person.ToModel())
+			return NewPerson(patched), err
+		})
+	case models.BatchDelete:
+		return Person{ID: personID}, r.deletePersonTx(ctx, tx, personID, operation.Version)
+	default:
+		return Person{}, errors.Errorf("unknown batch operation %q", operation.Type)
+	}
+}
+
+// ApplyBatch applies the operations in order in one transaction. If any of them fails, nothing is applied
+// and models.BatchOperationError with the index of the failed operation is returned.
+// The persons are aligned with the operations, only the ID is set for deleted ones.
+func (r *sqlxRepository) ApplyBatch(ctx context.Context, operations []models.BatchOperation) ([]models.Person, error) {
+	res := make([]models.Person, len(operations))
+
+	err := r.runChangeTx(ctx, func(tx *changeTx) error {
+		for i, operation := range operations {
+			person, err := r.applyBatchOperationTx(ctx, tx, operation, operation.Target(res))
+			if err != nil {
+				return models.BatchOperationError{Index: i, Err: mapError(err)}
+			}
+
+			res[i] = person.ToModel()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
 }
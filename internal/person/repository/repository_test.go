@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/repository"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/jmoiron/sqlx"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"log/slog"
+	"testing"
+)
+
+type RepositorySuite struct {
+	suite.Suite
+}
+
+var personColumns = []string{"id", "name", "age", "address", "work", "version", "deleted_at"}
+
+// newRepository returns the repository on the mocked database, the statements are expected in order.
+// age returns the pointer to the age of the person.
+func age(value int) *int {
+	return &value
+}
+
+func (*RepositorySuite) newRepository(t provider.T, matcher sqlmock.QueryMatcher) (usecase.Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
+	t.Require().NoError(err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return repository.NewSqlxRepository(sqlx.NewDb(db, "postgres"), slog.Default()), mock
+}
+
+func (*RepositorySuite) expectCreate(mock sqlmock.Sqlmock, personID int) {
+	mock.ExpectQuery(`insert into persons\(name`).
+		WillReturnRows(sqlmock.NewRows(personColumns).AddRow(personID, "Aboba", 20, "", "", 1, nil))
+	mock.ExpectExec(`insert into person_history`).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func (s *RepositorySuite) TestOutboxIsLockedBeforeCommit(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	repo, mock := s.newRepository(t, sqlmock.QueryMatcherRegexp)
+	mock.ExpectBegin()
+	s.expectCreate(mock, 1)
+	s.expectCreate(mock, 2)
+	mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`insert into outbox`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	create := models.BatchOperation{
+		Type:   models.BatchCreate,
+		Person: models.PersonProperties{Name: "Aboba", Age: age(20)},
+	}
+	// act
+	persons, err := repo.ApplyBatch(context.Background(), []models.BatchOperation{create, create})
+	// assert
+	t.Require().NoError(err)
+	t.Require().Len(persons, 2)
+	t.Require().NoError(mock.ExpectationsWereMet())
+}
+
+func (s *RepositorySuite) TestFailedChangeDoesNotLockOutbox(t provider.T) {
+	t.Epic("Outbox")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	repo, mock := s.newRepository(t, sqlmock.QueryMatcherRegexp)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select .* from persons where id=\$1`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(personColumns).AddRow(1, "Aboba", 20, "", "", 2, nil))
+	mock.ExpectRollback()
+	person := models.Person{ID: 1, PersonProperties: models.PersonProperties{Name: "Aboba", Age: age(21)}}
+	// act
+	_, err := repo.UpdatePerson(context.Background(), person, 1)
+	// assert
+	t.Require().ErrorIs(err, models.ErrVersionMismatch)
+	t.Require().NoError(mock.ExpectationsWereMet())
+}
+
+func (s *RepositorySuite) TestReplaceDeletedPerson(t provider.T) {
+	t.Epic("Persons")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	repo, mock := s.newRepository(t, sqlmock.QueryMatcherRegexp)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select .* from persons where id=\$1 and deleted_at is null`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(personColumns))
+	mock.ExpectQuery(`insert into persons\(id, .* on conflict \(id\) do nothing`).
+		WillReturnRows(sqlmock.NewRows(personColumns))
+	mock.ExpectRollback()
+	person := models.Person{ID: 1, PersonProperties: models.PersonProperties{Name: "Aboba", Age: age(20)}}
+	// act
+	_, created, err := repo.ReplacePerson(context.Background(), person, models.AnyVersion, true)
+	// assert
+	t.Require().ErrorIs(err, models.ErrPersonDeleted)
+	t.Require().Equal(usecase.KindConflict, usecase.KindOf(err))
+	t.Require().False(created)
+	t.Require().NoError(mock.ExpectationsWereMet())
+}
+
+func TestRepository(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(RepositorySuite))
+}
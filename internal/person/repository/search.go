@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/jmoiron/sqlx"
+	"strconv"
+)
+
+// headlineOptions marks the found words for the client and limits the highlight of long fields to the fragments.
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5, MaxFragments=2`
+
+func (r *sqlxRepository) SearchPersons(
+	ctx context.Context,
+	query models.PersonsSearchQuery,
+) (models.PersonsSearchPage, error) {
+	var results []PersonSearchResult
+
+	err := r.db.SelectContext(ctx, &results, searchPersonsQuery, query.Query, query.Offset, query.Limit+1, headlineOptions)
+	if err != nil {
+		return models.PersonsSearchPage{}, mapError(err)
+	}
+
+	page := models.PersonsSearchPage{
+		Results: make([]models.PersonSearchResult, 0, len(results)),
+	}
+
+	if int64(len(results)) > query.Limit {
+		results = results[:query.Limit]
+		page.HasMore = true
+	}
+
+	for _, result := range results {
+		page.Results = append(page.Results, result.ToModel())
+	}
+
+	return page, nil
+}
+
+// SuggestNames returns distinct names of persons ordered by similarity to the given name.
+// The threshold of the fuzzy mode is set for the transaction only, so the trigram index is used by the query.
+func (r *sqlxRepository) SuggestNames(ctx context.Context, query models.NameSuggestionQuery) ([]models.NameSuggestion, error) {
+	var suggestions []NameSuggestion
+
+	if query.Mode == models.SuggestPrefix {
+		prefix := likeEscaper.Replace(query.Name) + "%"
+
+		err := r.db.SelectContext(ctx, &suggestions, suggestNamesByPrefixQuery, query.Name, prefix, query.Limit)
+		if err != nil {
+			return nil, mapError(err)
+		}
+	} else {
+		err := runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+			threshold := strconv.FormatFloat(query.Threshold, 'f', -1, 64)
+
+			_, err := tx.ExecContext(ctx, setSimilarityThresholdQuery, threshold)
+			if err != nil {
+				return err
+			}
+
+			return tx.SelectContext(ctx, &suggestions, suggestNamesQuery, query.Name, query.Limit)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]models.NameSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		res = append(res, models.NameSuggestion(suggestion))
+	}
+
+	return res, nil
+}
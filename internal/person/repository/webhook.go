@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/person/webhook"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"log/slog"
+	"time"
+)
+
+// NewSqlxWebhookRepository returns the storage of the webhooks in the database of persons.
+func NewSqlxWebhookRepository(db *sqlx.DB, logger *slog.Logger) webhook.Repository {
+	return &sqlxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func mapWebhookError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return usecase.NotFound(models.ErrWebhookNotFound)
+	}
+
+	return mapError(err)
+}
+
+func (r *sqlxRepository) CreateWebhook(ctx context.Context, properties models.WebhookProperties) (models.Webhook, error) {
+	events := make([]string, 0, len(properties.Events))
+	for _, event := range properties.Events {
+		events = append(events, string(event))
+	}
+
+	var res Webhook
+
+	err := r.db.GetContext(ctx, &res, insertWebhookQuery, properties.URL, pq.StringArray(events), properties.Secret)
+	if err != nil {
+		return models.Webhook{}, mapError(err)
+	}
+
+	return res.ToModel(), nil
+}
+
+func (r *sqlxRepository) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []Webhook
+
+	err := r.db.SelectContext(ctx, &webhooks, selectWebhooksQuery)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	res := make([]models.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		res = append(res, webhook.ToModel())
+	}
+
+	return res, nil
+}
+
+func (r *sqlxRepository) GetWebhook(ctx context.Context, webhookID int) (models.Webhook, error) {
+	var res Webhook
+
+	err := r.db.GetContext(ctx, &res, selectWebhookQuery, webhookID)
+	if err != nil {
+		return models.Webhook{}, mapWebhookError(err)
+	}
+
+	return res.ToModel(), nil
+}
+
+// DeleteWebhook removes the webhook with its deliveries.
+func (r *sqlxRepository) DeleteWebhook(ctx context.Context, webhookID int) error {
+	res, err := r.db.ExecContext(ctx, deleteWebhookQuery, webhookID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return mapError(err)
+	}
+
+	if count == 0 {
+		return usecase.NotFound(models.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+func (r *sqlxRepository) GetWebhookDeliveries(
+	ctx context.Context,
+	query models.WebhookDeliveriesQuery,
+) (models.WebhookDeliveriesPage, error) {
+	var deliveries []WebhookDelivery
+
+	err := r.db.SelectContext(ctx, &deliveries, selectDeliveryQuery,
+		query.WebhookID, string(query.Status), query.Offset, query.Limit+1)
+	if err != nil {
+		return models.WebhookDeliveriesPage{}, mapError(err)
+	}
+
+	page := models.WebhookDeliveriesPage{
+		Deliveries: make([]models.WebhookDelivery, 0, len(deliveries)),
+	}
+
+	if int64(len(deliveries)) > query.Limit {
+		deliveries = deliveries[:query.Limit]
+		page.HasMore = true
+	}
+
+	for _, delivery := range deliveries {
+		page.Deliveries = append(page.Deliveries, delivery.ToModel())
+	}
+
+	return page, nil
+}
+
+// EnqueueWebhookDeliveries stores the deliveries of all events with one insert, in the order of the events.
+func (r *sqlxRepository) EnqueueWebhookDeliveries(ctx context.Context, events []models.WebhookEvent) error {
+	ids := make([]string, 0, len(events))
+	types := make([]string, 0, len(events))
+	payloads := make([]string, 0, len(events))
+
+	for _, event := range events {
+		ids = append(ids, event.ID)
+		types = append(types, string(event.Type))
+		payloads = append(payloads, string(event.Payload))
+	}
+
+	_, err := r.db.ExecContext(ctx, enqueueDeliveryQuery, pq.StringArray(ids), pq.StringArray(types), pq.StringArray(payloads))
+
+	return mapError(err)
+}
+
+func (r *sqlxRepository) ClaimWebhookDeliveries(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]models.WebhookDeliveryTask, error) {
+	var tasks []WebhookDeliveryTask
+
+	err := r.db.SelectContext(ctx, &tasks, claimDeliveriesQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	res := make([]models.WebhookDeliveryTask, 0, len(tasks))
+	for _, task := range tasks {
+		res = append(res, models.WebhookDeliveryTask{
+			Delivery: task.ToModel(),
+			URL:      task.URL,
+			Secret:   task.Secret,
+		})
+	}
+
+	return res, nil
+}
+
+func (r *sqlxRepository) UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	_, err := r.db.NamedExecContext(ctx, updateDeliveryQuery, NewWebhookDelivery(delivery))
+	return mapError(err)
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import "errors"
+
+// ErrorKind classifies the errors of the use cases, so that the delivery maps them onto its responses
+// without knowing the storage. Errors of unknown kinds are internal.
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	// KindNotFound is returned if the requested person does not exist.
+	KindNotFound
+	// KindConflict is returned if the request conflicts with the current state, e.g. with a concurrent change.
+	KindConflict
+	// KindValidation is returned if the request data is rejected by the domain rules or the storage constraints.
+	KindValidation
+	// KindUnavailable is returned if the storage cannot be reached, so the request may be retried later.
+	KindUnavailable
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation"
+	case KindUnavailable:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+// Error is the error of the given kind. The cause is kept for errors.Is checks of the domain errors and for the logs.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.String() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(err error) error {
+	return &Error{Kind: KindNotFound, Err: err}
+}
+
+func Conflict(err error) error {
+	return &Error{Kind: KindConflict, Err: err}
+}
+
+func Validation(err error) error {
+	return &Error{Kind: KindValidation, Err: err}
+}
+
+func Unavailable(err error) error {
+	return &Error{Kind: KindUnavailable, Err: err}
+}
+
+// KindOf returns the kind of the first Error in the chain or KindInternal if there is none.
+func KindOf(err error) ErrorKind {
+	var kindErr *Error
+	if errors.As(err, &kindErr) {
+		return kindErr.Kind
+	}
+
+	return KindInternal
+}
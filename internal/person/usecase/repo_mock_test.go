@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/Inspirate789/ds-lab1/internal/models"
 	"github.com/stretchr/testify/mock"
+	"iter"
+	"time"
 )
 
 type RepositoryPositiveMock struct {
@@ -15,9 +17,22 @@ func (r *RepositoryPositiveMock) HealthCheck(_ context.Context) error {
 	return args.Error(0)
 }
 
-func (r *RepositoryPositiveMock) GetPersons(_ context.Context, offset, limit int64) ([]models.Person, error) {
-	args := r.Called(offset, limit)
-	return args.Get(0).([]models.Person), args.Error(1)
+func (r *RepositoryPositiveMock) GetPersons(_ context.Context, query models.PersonsQuery) (models.PersonsPage, error) {
+	args := r.Called(query)
+	return args.Get(0).(models.PersonsPage), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) IteratePersons(
+	_ context.Context,
+	query models.PersonsQuery,
+) iter.Seq2[models.Person, error] {
+	args := r.Called(query)
+	return args.Get(0).(iter.Seq2[models.Person, error])
+}
+
+func (r *RepositoryPositiveMock) CountPersons(_ context.Context, filter models.PersonsFilter) (int64, error) {
+	args := r.Called(filter)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (r *RepositoryPositiveMock) CreatePerson(_ context.Context, person models.PersonProperties) (models.Person, error) {
@@ -25,17 +40,95 @@ func (r *RepositoryPositiveMock) CreatePerson(_ context.Context, person models.P
 	return args.Get(0).(models.Person), args.Error(1)
 }
 
-func (r *RepositoryPositiveMock) GetPerson(_ context.Context, personID int) (models.Person, bool, error) {
+func (r *RepositoryPositiveMock) CreatePersonOnce(
+	_ context.Context,
+	key models.IdempotencyKey,
+	person models.PersonProperties,
+) (models.IdempotentResult, error) {
+	args := r.Called(key, person)
+	return args.Get(0).(models.IdempotentResult), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) GetPerson(_ context.Context, personID int) (models.Person, error) {
 	args := r.Called(personID)
-	return args.Get(0).(models.Person), args.Bool(1), args.Error(2)
+	return args.Get(0).(models.Person), args.Error(1)
 }
 
-func (r *RepositoryPositiveMock) UpdatePerson(_ context.Context, person models.Person) (models.Person, bool, error) {
-	args := r.Called(person)
+func (r *RepositoryPositiveMock) UpdatePerson(_ context.Context, person models.Person, version int64) (models.Person, error) {
+	args := r.Called(person, version)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) PatchPerson(
+	_ context.Context,
+	personID int,
+	patch models.PersonPatch,
+	version int64,
+) (models.Person, error) {
+	args := r.Called(personID, patch, version)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) ReplacePerson(
+	_ context.Context,
+	person models.Person,
+	version int64,
+	createIfAbsent bool,
+) (models.Person, bool, error) {
+	args := r.Called(person, version, createIfAbsent)
 	return args.Get(0).(models.Person), args.Bool(1), args.Error(2)
 }
 
-func (r *RepositoryPositiveMock) DeletePerson(_ context.Context, personID int) (bool, error) {
+func (r *RepositoryPositiveMock) DeletePerson(_ context.Context, personID int, version int64) error {
+	args := r.Called(personID, version)
+	return args.Error(0)
+}
+
+func (r *RepositoryPositiveMock) RestorePerson(_ context.Context, personID int) (models.Person, error) {
 	args := r.Called(personID)
-	return args.Bool(0), args.Error(1)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) PurgeDeletedPersons(_ context.Context, olderThan time.Duration) (int64, error) {
+	args := r.Called(olderThan)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) DeleteExpiredIdempotencyKeys(_ context.Context) (int64, error) {
+	args := r.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) GetPersonHistory(
+	_ context.Context,
+	query models.PersonHistoryQuery,
+) (models.PersonHistoryPage, error) {
+	args := r.Called(query)
+	return args.Get(0).(models.PersonHistoryPage), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) CreatePersons(_ context.Context, persons []models.PersonProperties) ([]models.Person, error) {
+	args := r.Called(persons)
+	return args.Get(0).([]models.Person), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) ApplyBatch(_ context.Context, operations []models.BatchOperation) ([]models.Person, error) {
+	args := r.Called(operations)
+	return args.Get(0).([]models.Person), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) SearchPersons(
+	_ context.Context,
+	query models.PersonsSearchQuery,
+) (models.PersonsSearchPage, error) {
+	args := r.Called(query)
+	return args.Get(0).(models.PersonsSearchPage), args.Error(1)
+}
+
+func (r *RepositoryPositiveMock) SuggestNames(
+	_ context.Context,
+	query models.NameSuggestionQuery,
+) ([]models.NameSuggestion, error) {
+	args := r.Called(query)
+	return args.Get(0).([]models.NameSuggestion), args.Error(1)
 }
@@ -2,48 +2,263 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/audit"
+	"iter"
 	"log/slog"
+	"time"
 )
 
+// Repository is the storage of persons. Its errors are the errors of the use cases (see Error),
+// e.g. the missing person is reported as NotFound(models.ErrPersonNotFound).
 type Repository interface {
 	HealthCheck(ctx context.Context) error
-	GetPersons(ctx context.Context, offset, limit int64) ([]models.Person, error)
+	GetPersons(ctx context.Context, query models.PersonsQuery) (models.PersonsPage, error)
+	IteratePersons(ctx context.Context, query models.PersonsQuery) iter.Seq2[models.Person, error]
+	CountPersons(ctx context.Context, filter models.PersonsFilter) (int64, error)
 	CreatePerson(ctx context.Context, person models.PersonProperties) (models.Person, error)
-	GetPerson(ctx context.Context, personID int) (models.Person, bool, error)
-	UpdatePerson(ctx context.Context, person models.Person) (models.Person, bool, error)
-	DeletePerson(ctx context.Context, personID int) (bool, error)
+	CreatePersonOnce(ctx context.Context, key models.IdempotencyKey, person models.PersonProperties) (models.IdempotentResult, error)
+	CreatePersons(ctx context.Context, persons []models.PersonProperties) ([]models.Person, error)
+	ApplyBatch(ctx context.Context, operations []models.BatchOperation) ([]models.Person, error)
+	GetPerson(ctx context.Context, personID int) (models.Person, error)
+	UpdatePerson(ctx context.Context, person models.Person, version int64) (models.Person, error)
+	PatchPerson(ctx context.Context, personID int, patch models.PersonPatch, version int64) (models.Person, error)
+	ReplacePerson(ctx context.Context, person models.Person, version int64, createIfAbsent bool) (models.Person, bool, error)
+	DeletePerson(ctx context.Context, personID int, version int64) error
+	RestorePerson(ctx context.Context, personID int) (models.Person, error)
+	PurgeDeletedPersons(ctx context.Context, olderThan time.Duration) (int64, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+	GetPersonHistory(ctx context.Context, query models.PersonHistoryQuery) (models.PersonHistoryPage, error)
+	SearchPersons(ctx context.Context, query models.PersonsSearchQuery) (models.PersonsSearchPage, error)
+	SuggestNames(ctx context.Context, query models.NameSuggestionQuery) ([]models.NameSuggestion, error)
+}
+
+// purgeActor is recorded into the history of persons removed by the purge job.
+const purgeActor = "purge-job"
+
+type PurgeConfig struct {
+	Interval      time.Duration `koanf:"interval"`
+	RetentionDays int           `koanf:"retention_days"`
+}
+
+// defaultSimilarityThreshold is the default threshold of the pg_trgm extension.
+const defaultSimilarityThreshold = 0.3
+
+type SuggestConfig struct {
+	SimilarityThreshold float64 `koanf:"similarity_threshold"`
+}
+
+type Config struct {
+	IdempotencyKeyTTL time.Duration `koanf:"idempotency_key_ttl"`
+	Purge             PurgeConfig   `koanf:"purge"`
+	Suggest           SuggestConfig `koanf:"suggest"`
 }
 
 type UseCase struct {
 	repo   Repository
+	config Config
 	logger *slog.Logger
 }
 
-func New(repo Repository, logger *slog.Logger) *UseCase {
-	return &UseCase{repo: repo, logger: logger}
+func New(repo Repository, config Config, logger *slog.Logger) *UseCase {
+	return &UseCase{repo: repo, config: config, logger: logger}
 }
 
 func (u *UseCase) HealthCheck(ctx context.Context) error {
 	return u.repo.HealthCheck(ctx)
 }
 
-func (u *UseCase) GetPersons(ctx context.Context, offset, limit int64) ([]models.Person, error) {
-	return u.repo.GetPersons(ctx, offset, limit)
+func (u *UseCase) GetPersons(ctx context.Context, query models.PersonsQuery) (models.PersonsPage, error) {
+	page, err := u.repo.GetPersons(ctx, query)
+	if err != nil {
+		return models.PersonsPage{}, err
+	}
+
+	if query.WithTotal {
+		page.Total, err = u.repo.CountPersons(ctx, query.Filter)
+		if err != nil {
+			return models.PersonsPage{}, err
+		}
+	}
+
+	if !query.Keyset || len(page.Persons) == 0 {
+		return page, nil
+	}
+
+	first, last := page.Persons[0], page.Persons[len(page.Persons)-1]
+	backward := query.Cursor != nil && query.Cursor.Backward
+
+	if page.HasMore && backward || !backward && query.Cursor != nil {
+		page.PrevCursor = &models.Cursor{Backward: true, Boundary: first}
+	}
+
+	if page.HasMore && !backward || backward {
+		page.NextCursor = &models.Cursor{Boundary: last}
+	}
+
+	return page, nil
+}
+
+// StreamPersons returns persons matching the query one by one without loading all of them into memory.
+// Pagination cursors are not computed for the stream.
+func (u *UseCase) StreamPersons(ctx context.Context, query models.PersonsQuery) iter.Seq2[models.Person, error] {
+	return u.repo.IteratePersons(ctx, query)
 }
 
 func (u *UseCase) CreatePerson(ctx context.Context, person models.PersonProperties) (models.Person, error) {
 	return u.repo.CreatePerson(ctx, person)
 }
 
-func (u *UseCase) GetPerson(ctx context.Context, personID int) (models.Person, bool, error) {
+func (u *UseCase) CreatePersons(ctx context.Context, persons []models.PersonProperties) ([]models.Person, error) {
+	return u.repo.CreatePersons(ctx, persons)
+}
+
+// ApplyBatch applies the operations atomically. Operations may refer to the persons created by the earlier ones.
+func (u *UseCase) ApplyBatch(ctx context.Context, operations []models.BatchOperation) ([]models.Person, error) {
+	for i, operation := range operations {
+		if operation.Ref == nil {
+			continue
+		}
+
+		ref := *operation.Ref
+		if operation.Type == models.BatchCreate || ref < 0 || ref >= i || operations[ref].Type != models.BatchCreate {
+			return nil, models.BatchOperationError{Index: i, Err: Validation(models.ErrInvalidBatchRef)}
+		}
+	}
+
+	return u.repo.ApplyBatch(ctx, operations)
+}
+
+// requestFingerprint identifies the create request body to detect reuse of the idempotency key.
+func requestFingerprint(person models.PersonProperties) string {
+	hash := sha256.New()
+	_ = json.NewEncoder(hash).Encode(person) // cannot fail for this structure
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (u *UseCase) CreatePersonOnce(
+	ctx context.Context,
+	idempotencyKey string,
+	person models.PersonProperties,
+) (models.IdempotentResult, error) {
+	key := models.IdempotencyKey{
+		Key:         idempotencyKey,
+		Fingerprint: requestFingerprint(person),
+		TTL:         u.config.IdempotencyKeyTTL,
+	}
+
+	return u.repo.CreatePersonOnce(ctx, key, person)
+}
+
+func (u *UseCase) GetPerson(ctx context.Context, personID int) (models.Person, error) {
 	return u.repo.GetPerson(ctx, personID)
 }
 
-func (u *UseCase) UpdatePerson(ctx context.Context, person models.Person) (models.Person, bool, error) {
-	return u.repo.UpdatePerson(ctx, person)
+func (u *UseCase) UpdatePerson(ctx context.Context, person models.Person, version int64) (models.Person, error) {
+	return u.repo.UpdatePerson(ctx, person, version)
 }
 
-func (u *UseCase) DeletePerson(ctx context.Context, personID int) (bool, error) {
-	return u.repo.DeletePerson(ctx, personID)
+func (u *UseCase) PatchPerson(
+	ctx context.Context,
+	personID int,
+	patch models.PersonPatch,
+	version int64,
+) (models.Person, error) {
+	return u.repo.PatchPerson(ctx, personID, patch, version)
+}
+
+func (u *UseCase) ReplacePerson(
+	ctx context.Context,
+	person models.Person,
+	version int64,
+	createIfAbsent bool,
+) (models.Person, bool, error) {
+	return u.repo.ReplacePerson(ctx, person, version, createIfAbsent)
+}
+
+func (u *UseCase) DeletePerson(ctx context.Context, personID int, version int64) error {
+	return u.repo.DeletePerson(ctx, personID, version)
+}
+
+func (u *UseCase) RestorePerson(ctx context.Context, personID int) (models.Person, error) {
+	return u.repo.RestorePerson(ctx, personID)
+}
+
+// PurgeDeletedPersons permanently removes persons kept in the trash longer than the retention period.
+func (u *UseCase) PurgeDeletedPersons(ctx context.Context) (int64, error) {
+	retention := time.Duration(u.config.Purge.RetentionDays) * 24 * time.Hour
+
+	return u.repo.PurgeDeletedPersons(ctx, retention)
+}
+
+// DeleteExpiredIdempotencyKeys removes the idempotency keys whose results are not replayed anymore.
+func (u *UseCase) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	return u.repo.DeleteExpiredIdempotencyKeys(ctx)
+}
+
+// RunPurge purges deleted persons and expired idempotency keys periodically until the context is canceled.
+// It returns immediately if the purge interval is not set.
+func (u *UseCase) RunPurge(ctx context.Context) {
+	if u.config.Purge.Interval <= 0 {
+		return
+	}
+
+	ctx = audit.WithMetadata(ctx, audit.Metadata{Actor: purgeActor})
+
+	ticker := time.NewTicker(u.config.Purge.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.purge(ctx)
+		}
+	}
+}
+
+func (u *UseCase) purge(ctx context.Context) {
+	count, err := u.PurgeDeletedPersons(ctx)
+	if err != nil {
+		u.logger.Error("failed to purge deleted persons", slog.String("error", err.Error()))
+	} else {
+		u.logger.Info("deleted persons purged", slog.Int64("count", count))
+	}
+
+	count, err = u.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		u.logger.Error("failed to delete expired idempotency keys", slog.String("error", err.Error()))
+	} else {
+		u.logger.Info("expired idempotency keys deleted", slog.Int64("count", count))
+	}
+}
+
+func (u *UseCase) GetPersonHistory(
+	ctx context.Context,
+	query models.PersonHistoryQuery,
+) (models.PersonHistoryPage, error) {
+	return u.repo.GetPersonHistory(ctx, query)
+}
+
+func (u *UseCase) SearchPersons(ctx context.Context, query models.PersonsSearchQuery) (models.PersonsSearchPage, error) {
+	return u.repo.SearchPersons(ctx, query)
+}
+
+// SuggestNames returns the names similar to the given one. The configured similarity threshold
+// is used if the query does not set its own.
+func (u *UseCase) SuggestNames(ctx context.Context, query models.NameSuggestionQuery) ([]models.NameSuggestion, error) {
+	if query.Threshold <= 0 {
+		query.Threshold = u.config.Suggest.SimilarityThreshold
+	}
+
+	if query.Threshold <= 0 {
+		query.Threshold = defaultSimilarityThreshold
+	}
+
+	return u.repo.SuggestNames(ctx, query)
 }
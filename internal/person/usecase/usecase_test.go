@@ -2,21 +2,32 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
 	"github.com/Inspirate789/ds-lab1/internal/models"
 	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
 	"github.com/ozontech/allure-go/pkg/allure"
 	"github.com/ozontech/allure-go/pkg/framework/provider"
 	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"iter"
 	"log/slog"
+	"math"
 	"os"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 type UseCaseSuite struct {
 	suite.Suite
 }
 
+// age returns the pointer to the age of the person.
+func age(value int) *int {
+	return &value
+}
+
 func (s *UseCaseSuite) TestHealthCheck(t provider.T) {
 	t.Epic("MVP")
 	t.Severity(allure.NORMAL)
@@ -25,7 +36,7 @@ func (s *UseCaseSuite) TestHealthCheck(t provider.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	repo := new(RepositoryPositiveMock)
 	repo.On("HealthCheck").Return(nil)
-	useCase := usecase.New(repo, logger)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
 	// act
 	err := useCase.HealthCheck(context.Background())
 	// assert
@@ -39,7 +50,7 @@ func (*UseCaseSuite) newPerson(id int) models.Person {
 		ID: id,
 		PersonProperties: models.PersonProperties{
 			Name:    "Aboba " + strconv.Itoa(id),
-			Age:     id,
+			Age:     age(id),
 			Address: "Address " + strconv.Itoa(id),
 			Work:    "Work " + strconv.Itoa(id),
 		},
@@ -62,14 +73,85 @@ func (s *UseCaseSuite) TestGetPersons(t provider.T) {
 	}
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	repo := new(RepositoryPositiveMock)
-	repo.On("GetPersons", offset, limit).Return(persons[offset:][:limit], nil)
-	useCase := usecase.New(repo, logger)
+	query := models.PersonsQuery{
+		Filter: models.PersonsFilter{NameContains: "Aboba"},
+		Sort:   []models.SortKey{{Field: models.PersonFieldAge, Descending: true}},
+		Offset: offset,
+		Limit:  limit,
+	}
+	repo.On("GetPersons", query).Return(models.PersonsPage{Persons: persons[offset:][:limit], HasMore: true}, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.GetPersons(context.Background(), query)
+	// assert
+	t.Require().NoError(err)
+	t.Assert().Len(res.Persons, int(limit))
+	t.Assert().ElementsMatch(persons[offset:][:limit], res.Persons)
+	t.Assert().True(res.HasMore)
+	t.Assert().Nil(res.NextCursor)
+	t.Assert().Nil(res.PrevCursor)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "GetPersons", 1)
+}
+
+func (s *UseCaseSuite) TestGetPersonsWithTotal(t provider.T) {
+	t.Epic("Pagination")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const total int64 = 5
+	persons := []models.Person{
+		s.newPerson(1),
+		s.newPerson(2),
+	}
+	query := models.PersonsQuery{
+		Filter:    models.PersonsFilter{Work: "Work"},
+		Limit:     int64(len(persons)),
+		WithTotal: true,
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("GetPersons", query).Return(models.PersonsPage{Persons: persons, HasMore: true}, nil)
+	repo.On("CountPersons", query.Filter).Return(total, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.GetPersons(context.Background(), query)
+	// assert
+	t.Require().NoError(err)
+	t.Assert().Equal(persons, res.Persons)
+	t.Assert().Equal(total, res.Total)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "CountPersons", 1)
+}
+
+func (s *UseCaseSuite) TestGetPersonsKeyset(t provider.T) {
+	t.Epic("Pagination")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const limit int64 = 2
+	persons := []models.Person{
+		s.newPerson(3),
+		s.newPerson(4),
+	}
+	query := models.PersonsQuery{
+		Limit:  limit,
+		Keyset: true,
+		Cursor: &models.Cursor{Boundary: s.newPerson(2)},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("GetPersons", query).Return(models.PersonsPage{Persons: persons, HasMore: true}, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
 	// act
-	res, err := useCase.GetPersons(context.Background(), offset, limit)
+	res, err := useCase.GetPersons(context.Background(), query)
 	// assert
 	t.Require().NoError(err)
-	t.Assert().Len(res, int(limit))
-	t.Assert().ElementsMatch(persons[offset:][:limit], res)
+	t.Assert().Equal(persons, res.Persons)
+	t.Require().NotNil(res.NextCursor)
+	t.Assert().Equal(models.Cursor{Boundary: persons[1]}, *res.NextCursor)
+	t.Require().NotNil(res.PrevCursor)
+	t.Assert().Equal(models.Cursor{Backward: true, Boundary: persons[0]}, *res.PrevCursor)
 	repo.AssertExpectations(t)
 	repo.AssertNumberOfCalls(t, "GetPersons", 1)
 }
@@ -84,7 +166,7 @@ func (s *UseCaseSuite) TestCreatePerson(t provider.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	repo := new(RepositoryPositiveMock)
 	repo.On("CreatePerson", person.PersonProperties).Return(person, nil)
-	useCase := usecase.New(repo, logger)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
 	// act
 	res, err := useCase.CreatePerson(context.Background(), person.PersonProperties)
 	// assert
@@ -94,6 +176,148 @@ func (s *UseCaseSuite) TestCreatePerson(t provider.T) {
 	repo.AssertNumberOfCalls(t, "CreatePerson", 1)
 }
 
+func (s *UseCaseSuite) TestCreatePersonOnce(t provider.T) {
+	t.Epic("Idempotency")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const personID = 5
+	const idempotencyKey = "key"
+	person := s.newPerson(personID)
+	config := usecase.Config{IdempotencyKeyTTL: time.Hour}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("CreatePersonOnce", mock.MatchedBy(func(key models.IdempotencyKey) bool {
+		return key.Key == idempotencyKey && key.Fingerprint != "" && key.TTL == config.IdempotencyKeyTTL
+	}), person.PersonProperties).Return(models.IdempotentResult{PersonID: personID}, nil)
+	useCase := usecase.New(repo, config, logger)
+	// act
+	first, err1 := useCase.CreatePersonOnce(context.Background(), idempotencyKey, person.PersonProperties)
+	second, err2 := useCase.CreatePersonOnce(context.Background(), idempotencyKey, person.PersonProperties)
+	// assert
+	t.Require().NoError(err1)
+	t.Require().NoError(err2)
+	t.Require().Equal(personID, first.PersonID)
+	t.Require().Equal(first, second)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "CreatePersonOnce", 2)
+	t.Require().Equal(
+		repo.Calls[0].Arguments.Get(0).(models.IdempotencyKey).Fingerprint,
+		repo.Calls[1].Arguments.Get(0).(models.IdempotencyKey).Fingerprint,
+	)
+}
+
+func (s *UseCaseSuite) TestCreatePersons(t provider.T) {
+	t.Epic("Batch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	persons := []models.Person{
+		s.newPerson(1),
+		s.newPerson(2),
+	}
+	properties := []models.PersonProperties{
+		persons[0].PersonProperties,
+		persons[1].PersonProperties,
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("CreatePersons", properties).Return(persons, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.CreatePersons(context.Background(), properties)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(persons, res)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "CreatePersons", 1)
+}
+
+func (s *UseCaseSuite) TestApplyBatch(t provider.T) {
+	t.Epic("Batch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	ref := 0
+	operations := []models.BatchOperation{
+		{Type: models.BatchCreate, Person: s.newPerson(0).PersonProperties},
+		{Type: models.BatchDelete, Ref: &ref},
+	}
+	persons := []models.Person{s.newPerson(1), {ID: 1}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("ApplyBatch", operations).Return(persons, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.ApplyBatch(context.Background(), operations)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(persons, res)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "ApplyBatch", 1)
+}
+
+func (s *UseCaseSuite) TestApplyBatchInvalidRef(t provider.T) {
+	t.Epic("Batch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	ref := 1
+	operations := []models.BatchOperation{
+		{Type: models.BatchDelete, PersonID: 1},
+		{Type: models.BatchDelete, Ref: &ref},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	_, err := useCase.ApplyBatch(context.Background(), operations)
+	// assert
+	var batchErr models.BatchOperationError
+	t.Require().ErrorAs(err, &batchErr)
+	t.Require().Equal(1, batchErr.Index)
+	t.Require().ErrorIs(err, models.ErrInvalidBatchRef)
+	t.Require().Equal(usecase.KindValidation, usecase.KindOf(err))
+	repo.AssertNotCalled(t, "ApplyBatch", operations)
+}
+
+func (s *UseCaseSuite) TestStreamPersons(t provider.T) {
+	t.Epic("Streaming")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	persons := []models.Person{
+		s.newPerson(1),
+		s.newPerson(2),
+		s.newPerson(3),
+	}
+	query := models.PersonsQuery{
+		Filter: models.PersonsFilter{NameContains: "Aboba"},
+		Limit:  math.MaxInt64,
+	}
+	stream := func(yield func(models.Person, error) bool) {
+		for _, person := range persons {
+			if !yield(person, nil) {
+				return
+			}
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("IteratePersons", query).Return(iter.Seq2[models.Person, error](stream))
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	var streamed []models.Person
+	// act
+	for person, err := range useCase.StreamPersons(context.Background(), query) {
+		t.Require().NoError(err)
+		streamed = append(streamed, person)
+	}
+	// assert
+	t.Require().Equal(persons, streamed)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "IteratePersons", 1)
+}
+
 func (s *UseCaseSuite) TestGetPerson(t provider.T) {
 	t.Epic("MVP")
 	t.Severity(allure.NORMAL)
@@ -103,18 +327,36 @@ func (s *UseCaseSuite) TestGetPerson(t provider.T) {
 	person := s.newPerson(personID)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	repo := new(RepositoryPositiveMock)
-	repo.On("GetPerson", person.ID).Return(person, true, nil)
-	useCase := usecase.New(repo, logger)
+	repo.On("GetPerson", person.ID).Return(person, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
 	// act
-	res, found, err := useCase.GetPerson(context.Background(), person.ID)
+	res, err := useCase.GetPerson(context.Background(), person.ID)
 	// assert
 	t.Require().NoError(err)
-	t.Require().True(found)
 	t.Require().Equal(person, res)
 	repo.AssertExpectations(t)
 	repo.AssertNumberOfCalls(t, "GetPerson", 1)
 }
 
+func (s *UseCaseSuite) TestGetPersonNotFound(t provider.T) {
+	t.Epic("Errors")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const personID = 5
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("GetPerson", personID).Return(models.Person{}, usecase.NotFound(models.ErrPersonNotFound))
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	_, err := useCase.GetPerson(context.Background(), personID)
+	// assert
+	t.Require().ErrorIs(err, models.ErrPersonNotFound)
+	t.Require().Equal(usecase.KindNotFound, usecase.KindOf(err))
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "GetPerson", 1)
+}
+
 func (s *UseCaseSuite) TestUpdatePerson(t provider.T) {
 	t.Epic("MVP")
 	t.Severity(allure.NORMAL)
@@ -130,18 +372,69 @@ func (s *UseCaseSuite) TestUpdatePerson(t provider.T) {
 	newPerson.Work = "New Work"
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	repo := new(RepositoryPositiveMock)
-	repo.On("UpdatePerson", personOverride).Return(newPerson, true, nil)
-	useCase := usecase.New(repo, logger)
+	repo.On("UpdatePerson", personOverride, models.AnyVersion).Return(newPerson, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
 	// act
-	res, found, err := useCase.UpdatePerson(context.Background(), personOverride)
+	res, err := useCase.UpdatePerson(context.Background(), personOverride, models.AnyVersion)
 	// assert
 	t.Require().NoError(err)
-	t.Require().True(found)
 	t.Require().Equal(newPerson, res)
 	repo.AssertExpectations(t)
 	repo.AssertNumberOfCalls(t, "UpdatePerson", 1)
 }
 
+type workPatch string
+
+func (w workPatch) Apply(person models.Person) (models.Person, error) {
+	person.Work = string(w)
+	return person, nil
+}
+
+func (s *UseCaseSuite) TestPatchPerson(t provider.T) {
+	t.Epic("Patch")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const personID = 5
+	const version int64 = 3
+	person := s.newPerson(personID)
+	patch := workPatch("")
+	newPerson, _ := patch.Apply(person)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("PatchPerson", personID, patch, version).Return(newPerson, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.PatchPerson(context.Background(), personID, patch, version)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(newPerson, res)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "PatchPerson", 1)
+}
+
+func (s *UseCaseSuite) TestReplacePerson(t provider.T) {
+	t.Epic("Replace")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const personID = 5
+	person := s.newPerson(personID)
+	person.Work = ""
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("ReplacePerson", person, models.AnyVersion, true).Return(person, true, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, created, err := useCase.ReplacePerson(context.Background(), person, models.AnyVersion, true)
+	// assert
+	t.Require().NoError(err)
+	t.Require().True(created)
+	t.Require().Equal(person, res)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "ReplacePerson", 1)
+}
+
 func (s *UseCaseSuite) TestDeletePerson(t provider.T) {
 	t.Epic("MVP")
 	t.Severity(allure.NORMAL)
@@ -150,17 +443,168 @@ func (s *UseCaseSuite) TestDeletePerson(t provider.T) {
 	const personID = 5
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	repo := new(RepositoryPositiveMock)
-	repo.On("DeletePerson", personID).Return(true, nil)
-	useCase := usecase.New(repo, logger)
+	repo.On("DeletePerson", personID, models.AnyVersion).Return(nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
 	// act
-	found, err := useCase.DeletePerson(context.Background(), personID)
+	err := useCase.DeletePerson(context.Background(), personID, models.AnyVersion)
 	// assert
 	t.Require().NoError(err)
-	t.Require().True(found)
 	repo.AssertExpectations(t)
 	repo.AssertNumberOfCalls(t, "DeletePerson", 1)
 }
 
+func (s *UseCaseSuite) TestRestorePerson(t provider.T) {
+	t.Epic("Trash")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const personID = 5
+	person := s.newPerson(personID)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("RestorePerson", personID).Return(person, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.RestorePerson(context.Background(), personID)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(person, res)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "RestorePerson", 1)
+}
+
+func (s *UseCaseSuite) TestPurgeDeletedPersons(t provider.T) {
+	t.Epic("Trash")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const purged int64 = 2
+	config := usecase.Config{Purge: usecase.PurgeConfig{RetentionDays: 30}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("PurgeDeletedPersons", 30*24*time.Hour).Return(purged, nil)
+	useCase := usecase.New(repo, config, logger)
+	// act
+	count, err := useCase.PurgeDeletedPersons(context.Background())
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(purged, count)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "PurgeDeletedPersons", 1)
+}
+
+func (s *UseCaseSuite) TestRunPurge(t provider.T) {
+	t.Epic("Trash")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	config := usecase.Config{Purge: usecase.PurgeConfig{Interval: time.Millisecond, RetentionDays: 30}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	purged := make(chan struct{})
+	var purgedOnce sync.Once
+	repo.On("PurgeDeletedPersons", 30*24*time.Hour).Return(int64(0), errors.New("connection refused"))
+	repo.On("DeleteExpiredIdempotencyKeys").Return(int64(1), nil).Run(func(mock.Arguments) {
+		purgedOnce.Do(func() { close(purged) })
+	})
+	useCase := usecase.New(repo, config, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	// act
+	go func() {
+		useCase.RunPurge(ctx)
+		close(done)
+	}()
+	<-purged
+	cancel()
+	<-done
+	// assert
+	repo.AssertCalled(t, "PurgeDeletedPersons", 30*24*time.Hour)
+	repo.AssertCalled(t, "DeleteExpiredIdempotencyKeys")
+}
+
+func (s *UseCaseSuite) TestGetPersonHistory(t provider.T) {
+	t.Epic("History")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	const personID = 5
+	before := s.newPerson(personID)
+	after := before
+	after.Address = "New Address"
+	query := models.PersonHistoryQuery{PersonID: personID, Limit: 10}
+	page := models.PersonHistoryPage{Entries: []models.PersonHistoryEntry{{
+		PersonID:  personID,
+		Operation: models.HistoryUpdate,
+		Before:    &before,
+		After:     &after,
+		Actor:     "admin",
+	}}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("GetPersonHistory", query).Return(page, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.GetPersonHistory(context.Background(), query)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(page, res)
+	t.Require().Equal([]models.FieldChange{{
+		Field:  "address",
+		Before: before.Address,
+		After:  after.Address,
+	}}, res.Entries[0].Changes())
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "GetPersonHistory", 1)
+}
+
+func (s *UseCaseSuite) TestSearchPersons(t provider.T) {
+	t.Epic("Search")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	query := models.PersonsSearchQuery{Query: "Aboba", Limit: 10}
+	page := models.PersonsSearchPage{Results: []models.PersonSearchResult{{
+		Person:     s.newPerson(1),
+		Rank:       0.6,
+		Highlights: map[models.PersonField]string{models.PersonFieldName: "<mark>Aboba</mark>"},
+	}}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("SearchPersons", query).Return(page, nil)
+	useCase := usecase.New(repo, usecase.Config{}, logger)
+	// act
+	res, err := useCase.SearchPersons(context.Background(), query)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(page, res)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "SearchPersons", 1)
+}
+
+func (s *UseCaseSuite) TestSuggestNames(t provider.T) {
+	t.Epic("Search")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	query := models.NameSuggestionQuery{Name: "Aboda", Mode: models.SuggestFuzzy, Limit: 10}
+	expectedQuery := query
+	expectedQuery.Threshold = 0.5
+	suggestions := []models.NameSuggestion{{Name: "Aboba", Similarity: 0.6}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := new(RepositoryPositiveMock)
+	repo.On("SuggestNames", expectedQuery).Return(suggestions, nil)
+	config := usecase.Config{Suggest: usecase.SuggestConfig{SimilarityThreshold: 0.5}}
+	useCase := usecase.New(repo, config, logger)
+	// act
+	res, err := useCase.SuggestNames(context.Background(), query)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(suggestions, res)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "SuggestNames", 1)
+}
+
 func TestUseCase(t *testing.T) {
 	t.Parallel()
 
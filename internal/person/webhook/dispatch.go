@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+	// maxResponseSize limits the response body read to reuse the connection.
+	maxResponseSize = 64 << 10
+)
+
+// Sign returns the signature of the delivery: the hex-encoded HMAC-SHA256 of the timestamp and the body
+// joined with a dot. The timestamp is signed to reject the replays of the old deliveries.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run sends the pending deliveries periodically until the context is canceled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.Dispatch(ctx)
+			if err != nil {
+				s.logger.Error("failed to dispatch webhook deliveries", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Dispatch sends the batch of the pending deliveries concurrently and stores the results of the attempts.
+func (s *Service) Dispatch(ctx context.Context) error {
+	// the lease covers the request and the update of the delivery
+	lease := 2 * s.config.Timeout
+
+	tasks, err := s.repo.ClaimWebhookDeliveries(ctx, s.config.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+
+	for _, task := range tasks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			delivery := s.attempt(ctx, task)
+			if ctx.Err() != nil {
+				return // the attempt is interrupted by the shutdown, it is retried when the lease expires
+			}
+
+			err := s.repo.UpdateWebhookDelivery(ctx, delivery)
+			if err != nil {
+				s.logger.Error("failed to update webhook delivery",
+					slog.Int64("delivery_id", delivery.ID), slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt after the given number of the failed ones.
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.config.InitialBackoff
+
+	for i := 1; i < attempts && delay < s.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, s.config.MaxBackoff)
+}
+
+// attempt sends the delivery and returns it with the result of the attempt.
+func (s *Service) attempt(ctx context.Context, task models.WebhookDeliveryTask) models.WebhookDelivery {
+	delivery := task.Delivery
+	delivery.Attempts++
+
+	status, err := s.send(ctx, task)
+	delivery.LastStatus = status
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+
+		return delivery
+	}
+
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts >= s.config.MaxAttempts {
+		delivery.Status = models.DeliveryDead
+		s.logger.Warn("webhook delivery is dead",
+			slog.Int64("delivery_id", delivery.ID), slog.String("error", delivery.LastError))
+
+		return delivery
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.NextAttemptAt = time.Now().Add(s.backoff(delivery.Attempts))
+
+	return delivery
+}
+
+// send posts the signed payload to the webhook. Any 2xx status is the success.
+func (s *Service) send(ctx context.Context, task models.WebhookDeliveryTask) (int, error) {
+	delivery := task.Delivery
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(task.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
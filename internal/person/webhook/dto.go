@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"time"
+)
+
+type personDTO struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Age       *int       `json:"age"`
+	Address   string     `json:"address"`
+	Work      string     `json:"work"`
+	Version   int64      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// eventDTO is the body of the delivery. The person is omitted from the deleted events.
+type eventDTO struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	OccurredAt time.Time  `json:"occurred_at"`
+	PersonID   int        `json:"person_id"`
+	Person     *personDTO `json:"person,omitempty"`
+}
+
+func newEventDTO(event models.PersonEvent) eventDTO {
+	dto := eventDTO{
+		ID:         event.ID,
+		Type:       string(event.Type),
+		OccurredAt: event.OccurredAt.UTC(),
+		PersonID:   event.Person.ID,
+	}
+
+	if event.Type != models.PersonDeleted {
+		dto.Person = &personDTO{
+			ID:        event.Person.ID,
+			Name:      event.Person.Name,
+			Age:       event.Person.Age,
+			Address:   event.Person.Address,
+			Work:      event.Person.Work,
+			Version:   event.Person.Version,
+			DeletedAt: event.Person.DeletedAt,
+		}
+	}
+
+	return dto
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	dialTimeout   = 30 * time.Second
+	dialKeepAlive = 30 * time.Second
+)
+
+// publicAddress reports whether the address is routable on the internet. The callback URLs are set by the clients,
+// so the loopback, link-local, private and unspecified addresses are rejected to keep the clients from reaching
+// the internal services through the webhooks.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// checkURL checks that the host of the callback URL resolves to the public addresses only.
+func (s *Service) checkURL(ctx context.Context, rawURL string) error {
+	if s.config.AllowPrivateNetworks {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return usecase.Validation(fmt.Errorf("%w: %w", models.ErrForbiddenWebhookURL, err))
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return usecase.Validation(fmt.Errorf("%w: %w", models.ErrForbiddenWebhookURL, err))
+	}
+
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return usecase.Validation(fmt.Errorf("%w: %s resolves to %s", models.ErrForbiddenWebhookURL, u.Hostname(), addr))
+		}
+	}
+
+	return nil
+}
+
+// dialControl rejects the connections to the addresses which are not public. The host may resolve to another
+// address at the time of the delivery than at the registration of the webhook.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", models.ErrForbiddenWebhookURL, address)
+	}
+
+	return nil
+}
+
+// newHTTPClient returns the client of the callback URLs. The redirects are not followed, they could lead
+// to the addresses rejected at the registration, so the redirect is a failed attempt.
+func newHTTPClient(config Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if !config.AllowPrivateNetworks {
+		dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive, Control: dialControl}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil // the proxy would connect to the callback URL without the check
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
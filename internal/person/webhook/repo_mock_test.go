@@ -0,0 +1,59 @@
+package webhook_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (r *RepositoryMock) CreateWebhook(_ context.Context, webhook models.WebhookProperties) (models.Webhook, error) {
+	args := r.Called(webhook)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (r *RepositoryMock) GetWebhooks(_ context.Context) ([]models.Webhook, error) {
+	args := r.Called()
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (r *RepositoryMock) GetWebhook(_ context.Context, webhookID int) (models.Webhook, error) {
+	args := r.Called(webhookID)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (r *RepositoryMock) DeleteWebhook(_ context.Context, webhookID int) error {
+	args := r.Called(webhookID)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) GetWebhookDeliveries(
+	_ context.Context,
+	query models.WebhookDeliveriesQuery,
+) (models.WebhookDeliveriesPage, error) {
+	args := r.Called(query)
+	return args.Get(0).(models.WebhookDeliveriesPage), args.Error(1)
+}
+
+func (r *RepositoryMock) EnqueueWebhookDeliveries(_ context.Context, events []models.WebhookEvent) error {
+	args := r.Called(events)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) ClaimWebhookDeliveries(
+	_ context.Context,
+	limit int,
+	lease time.Duration,
+) ([]models.WebhookDeliveryTask, error) {
+	args := r.Called(limit, lease)
+	return args.Get(0).([]models.WebhookDeliveryTask), args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateWebhookDelivery(_ context.Context, delivery models.WebhookDelivery) error {
+	args := r.Called(delivery)
+	return args.Error(0)
+}
@@ -0,0 +1,184 @@
+// Package webhook notifies the subscribed callback URLs about the changes of persons. The events published
+// by the outbox relay are stored as the deliveries of the subscribed webhooks and sent asynchronously
+// by the dispatcher with the retries.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/pkg/errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Repository is the storage of the webhooks and their deliveries. Its errors are the errors of the person use cases,
+// e.g. the missing webhook is reported as usecase.NotFound(models.ErrWebhookNotFound).
+type Repository interface {
+	CreateWebhook(ctx context.Context, webhook models.WebhookProperties) (models.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, webhookID int) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID int) error
+	GetWebhookDeliveries(ctx context.Context, query models.WebhookDeliveriesQuery) (models.WebhookDeliveriesPage, error)
+	// EnqueueWebhookDeliveries stores the deliveries of the events to the webhooks subscribed to their types.
+	// The deliveries already stored for the events are skipped.
+	EnqueueWebhookDeliveries(ctx context.Context, events []models.WebhookEvent) error
+	// ClaimWebhookDeliveries returns the pending deliveries due to be sent. They are not claimed again
+	// until the lease expires, so the deliveries of the crashed dispatcher are sent by another one.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDeliveryTask, error)
+	// UpdateWebhookDelivery stores the result of the attempt to send the delivery.
+	UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
+
+const (
+	defaultPollInterval   = time.Second
+	defaultBatchSize      = 100
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = 10 * time.Second
+	defaultMaxBackoff     = time.Hour
+	defaultTimeout        = 10 * time.Second
+
+	secretLength = 32
+)
+
+type Config struct {
+	PollInterval time.Duration `koanf:"poll_interval"`
+	BatchSize    int           `koanf:"batch_size"`
+	// MaxAttempts is the number of attempts after which the delivery is dead.
+	MaxAttempts int `koanf:"max_attempts"`
+	// InitialBackoff is the delay before the first retry, it is doubled for every next retry up to MaxBackoff.
+	InitialBackoff time.Duration `koanf:"initial_backoff"`
+	MaxBackoff     time.Duration `koanf:"max_backoff"`
+	// Timeout limits the request to the callback URL.
+	Timeout time.Duration `koanf:"timeout"`
+	// AllowPrivateNetworks allows the callback URLs on the loopback, link-local and private addresses,
+	// e.g. for the development.
+	AllowPrivateNetworks bool `koanf:"allow_private_networks"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = defaultInitialBackoff
+	}
+
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+
+	return c
+}
+
+type Service struct {
+	repo       Repository
+	config     Config
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+func New(repo Repository, config Config, logger *slog.Logger) *Service {
+	config = config.withDefaults()
+
+	return &Service{
+		repo:       repo,
+		config:     config,
+		httpClient: newHTTPClient(config),
+		logger:     logger,
+	}
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, secretLength)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// CreateWebhook subscribes the webhook to the events. The secret is generated if it is not set.
+// The URL is rejected if its host resolves to the address which is not public.
+func (s *Service) CreateWebhook(ctx context.Context, webhook models.WebhookProperties) (models.Webhook, error) {
+	err := s.checkURL(ctx, webhook.URL)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if webhook.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return models.Webhook{}, err
+		}
+
+		webhook.Secret = secret
+	}
+
+	return s.repo.CreateWebhook(ctx, webhook)
+}
+
+func (s *Service) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return s.repo.GetWebhooks(ctx)
+}
+
+func (s *Service) GetWebhook(ctx context.Context, webhookID int) (models.Webhook, error) {
+	return s.repo.GetWebhook(ctx, webhookID)
+}
+
+// DeleteWebhook unsubscribes the webhook, its pending deliveries are not sent.
+func (s *Service) DeleteWebhook(ctx context.Context, webhookID int) error {
+	return s.repo.DeleteWebhook(ctx, webhookID)
+}
+
+// GetWebhookDeliveries returns the delivery log of the webhook, the latest deliveries first.
+func (s *Service) GetWebhookDeliveries(
+	ctx context.Context,
+	query models.WebhookDeliveriesQuery,
+) (models.WebhookDeliveriesPage, error) {
+	_, err := s.repo.GetWebhook(ctx, query.WebhookID)
+	if err != nil {
+		return models.WebhookDeliveriesPage{}, err
+	}
+
+	return s.repo.GetWebhookDeliveries(ctx, query)
+}
+
+// Publish stores the deliveries of the events dispatched from the outbox, they are sent by the dispatcher.
+// The deliveries are identified by the IDs of the events, so the events published again are stored once.
+func (s *Service) Publish(ctx context.Context, events []models.OutboxEvent) error {
+	webhookEvents := make([]models.WebhookEvent, 0, len(events))
+
+	for _, event := range events {
+		payload, err := json.Marshal(newEventDTO(event.PersonEvent))
+		if err != nil {
+			return errors.Wrap(err, "encode person event")
+		}
+
+		webhookEvents = append(webhookEvents, models.WebhookEvent{
+			ID:      event.ID,
+			Type:    event.Type,
+			Payload: payload,
+		})
+	}
+
+	return errors.Wrap(s.repo.EnqueueWebhookDeliveries(ctx, webhookEvents), "enqueue webhook deliveries")
+}
@@ -0,0 +1,375 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/person/webhook"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type WebhookSuite struct {
+	suite.Suite
+}
+
+const (
+	testSecret      = "0123456789abcdef"
+	testMaxAttempts = 3
+	testBackoff     = time.Minute
+)
+
+// newService returns the service of the repository. The private networks are allowed for the receivers
+// listening on the loopback address.
+// age returns the pointer to the age of the person.
+func age(value int) *int {
+	return &value
+}
+
+func (*WebhookSuite) newService(repo webhook.Repository, allowPrivateNetworks bool) *webhook.Service {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	return webhook.New(repo, webhook.Config{
+		MaxAttempts:          testMaxAttempts,
+		InitialBackoff:       testBackoff,
+		MaxBackoff:           time.Hour,
+		Timeout:              time.Second,
+		AllowPrivateNetworks: allowPrivateNetworks,
+	}, logger)
+}
+
+// receivedDelivery is the request received by the callback URL.
+type receivedDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver returns the callback URL responding with the status and the channel of the received requests.
+func (*WebhookSuite) newReceiver(status int) (*httptest.Server, <-chan receivedDelivery) {
+	received := make(chan receivedDelivery, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedDelivery{header: r.Header.Clone(), body: body}
+
+		w.WriteHeader(status)
+	}))
+
+	return server, received
+}
+
+func (*WebhookSuite) newTask(url string, attempts int) models.WebhookDeliveryTask {
+	return models.WebhookDeliveryTask{
+		Delivery: models.WebhookDelivery{
+			ID:        42,
+			WebhookID: 1,
+			EventID:   "4b5b1f7e-6f0e-4c53-9d1b-7d0f3c6a2a10",
+			EventType: models.PersonCreated,
+			Payload:   []byte(`{"type":"person.created","person_id":1}`),
+			Status:    models.DeliveryPending,
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: testSecret,
+	}
+}
+
+func (s *WebhookSuite) TestCreateWebhookGeneratesSecret(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	properties := models.WebhookProperties{
+		URL:    "https://example.com/hook",
+		Events: []models.PersonEventType{models.PersonCreated},
+	}
+	repo := new(RepositoryMock)
+	repo.On("CreateWebhook", mock.MatchedBy(func(webhook models.WebhookProperties) bool {
+		return webhook.URL == properties.URL && len(webhook.Secret) == 64
+	})).Return(models.Webhook{ID: 1, WebhookProperties: properties}, nil)
+	service := s.newService(repo, true)
+	// act
+	res, err := service.CreateWebhook(context.Background(), properties)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(1, res.ID)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "CreateWebhook", 1)
+}
+
+func (s *WebhookSuite) TestCreateWebhookRejectsPrivateURL(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.CRITICAL)
+
+	urls := []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[fe80::1]/hook",
+	}
+
+	for _, url := range urls {
+		t.Run(url, func(t provider.T) {
+			// arrange
+			properties := models.WebhookProperties{URL: url, Events: []models.PersonEventType{models.PersonCreated}}
+			repo := new(RepositoryMock)
+			service := s.newService(repo, false)
+			// act
+			_, err := service.CreateWebhook(context.Background(), properties)
+			// assert
+			t.Require().ErrorIs(err, models.ErrForbiddenWebhookURL)
+			t.Require().Equal(usecase.KindValidation, usecase.KindOf(err))
+			repo.AssertNotCalled(t, "CreateWebhook", mock.Anything)
+		})
+	}
+}
+
+func (s *WebhookSuite) TestPublish(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	occurredAt := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	deletedAt := occurredAt
+	events := []models.OutboxEvent{
+		{
+			Sequence: 1,
+			PersonEvent: models.PersonEvent{
+				ID:         "created",
+				Type:       models.PersonCreated,
+				Person:     models.Person{ID: 1, PersonProperties: models.PersonProperties{Name: "Aboba", Age: age(20)}},
+				OccurredAt: occurredAt,
+			},
+		},
+		{
+			Sequence: 2,
+			PersonEvent: models.PersonEvent{
+				ID:         "deleted",
+				Type:       models.PersonDeleted,
+				Person:     models.Person{ID: 2, Version: 3, DeletedAt: &deletedAt},
+				OccurredAt: occurredAt,
+			},
+		},
+	}
+	var enqueued []models.WebhookEvent
+	repo := new(RepositoryMock)
+	repo.On("EnqueueWebhookDeliveries", mock.Anything).Run(func(args mock.Arguments) {
+		enqueued = args.Get(0).([]models.WebhookEvent)
+	}).Return(nil)
+	service := s.newService(repo, true)
+	// act
+	err := service.Publish(context.Background(), events)
+	// assert
+	t.Require().NoError(err)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "EnqueueWebhookDeliveries", 1)
+	t.Require().Len(enqueued, 2)
+	t.Require().Equal("created", enqueued[0].ID)
+	t.Require().Equal(models.PersonCreated, enqueued[0].Type)
+	t.Require().JSONEq(`{"id":"created","type":"person.created","occurred_at":"2024-01-02T03:04:05Z","person_id":1,
+		"person":{"id":1,"name":"Aboba","age":20,"address":"","work":"","version":0}}`, string(enqueued[0].Payload))
+	t.Require().Equal("deleted", enqueued[1].ID)
+	t.Require().Equal(models.PersonDeleted, enqueued[1].Type)
+	t.Require().JSONEq(`{"id":"deleted","type":"person.deleted","occurred_at":"2024-01-02T03:04:05Z","person_id":2}`,
+		string(enqueued[1].Payload))
+}
+
+func (s *WebhookSuite) TestPublishFailsOnStorageError(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	repo := new(RepositoryMock)
+	repo.On("EnqueueWebhookDeliveries", mock.Anything).Return(errors.New("connection refused"))
+	service := s.newService(repo, true)
+	// act
+	err := service.Publish(context.Background(), []models.OutboxEvent{{Sequence: 1}})
+	// assert
+	t.Require().Error(err)
+	repo.AssertExpectations(t)
+}
+
+func (s *WebhookSuite) TestDispatchSignedDelivery(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	server, received := s.newReceiver(http.StatusNoContent)
+	defer server.Close()
+	task := s.newTask(server.URL, 0)
+	var updated models.WebhookDelivery
+	repo := new(RepositoryMock)
+	repo.On("ClaimWebhookDeliveries", mock.Anything, mock.Anything).Return([]models.WebhookDeliveryTask{task}, nil)
+	repo.On("UpdateWebhookDelivery", mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(models.WebhookDelivery)
+	}).Return(nil)
+	service := s.newService(repo, true)
+	// act
+	err := service.Dispatch(context.Background())
+	// assert
+	t.Require().NoError(err)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "UpdateWebhookDelivery", 1)
+	request := <-received
+	timestamp := request.header.Get(webhook.HeaderTimestamp)
+	t.Require().Equal(string(task.Delivery.Payload), string(request.body))
+	t.Require().Equal(string(models.PersonCreated), request.header.Get(webhook.HeaderEvent))
+	t.Require().Equal(strconv.FormatInt(task.Delivery.ID, 10), request.header.Get(webhook.HeaderDelivery))
+	t.Require().Equal(webhook.Sign(testSecret, timestamp, request.body), request.header.Get(webhook.HeaderSignature))
+	t.Require().Equal(models.DeliveryDelivered, updated.Status)
+	t.Require().Equal(1, updated.Attempts)
+	t.Require().Equal(http.StatusNoContent, updated.LastStatus)
+	t.Require().NotNil(updated.DeliveredAt)
+}
+
+func (s *WebhookSuite) TestDispatchRetry(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	server, received := s.newReceiver(http.StatusInternalServerError)
+	defer server.Close()
+	task := s.newTask(server.URL, 1)
+	var updated models.WebhookDelivery
+	repo := new(RepositoryMock)
+	repo.On("ClaimWebhookDeliveries", mock.Anything, mock.Anything).Return([]models.WebhookDeliveryTask{task}, nil)
+	repo.On("UpdateWebhookDelivery", mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(models.WebhookDelivery)
+	}).Return(nil)
+	service := s.newService(repo, true)
+	// act
+	before := time.Now()
+	err := service.Dispatch(context.Background())
+	// assert
+	t.Require().NoError(err)
+	repo.AssertExpectations(t)
+	<-received
+	t.Require().Equal(models.DeliveryPending, updated.Status)
+	t.Require().Equal(2, updated.Attempts)
+	t.Require().Equal(http.StatusInternalServerError, updated.LastStatus)
+	t.Require().NotEmpty(updated.LastError)
+	t.Require().False(updated.NextAttemptAt.Before(before.Add(2 * testBackoff)))
+	t.Require().False(updated.NextAttemptAt.After(time.Now().Add(2 * testBackoff)))
+	t.Require().Nil(updated.DeliveredAt)
+}
+
+func (s *WebhookSuite) TestDispatchDeadLetter(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	server, received := s.newReceiver(http.StatusBadGateway)
+	defer server.Close()
+	task := s.newTask(server.URL, testMaxAttempts-1)
+	var updated models.WebhookDelivery
+	repo := new(RepositoryMock)
+	repo.On("ClaimWebhookDeliveries", mock.Anything, mock.Anything).Return([]models.WebhookDeliveryTask{task}, nil)
+	repo.On("UpdateWebhookDelivery", mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(models.WebhookDelivery)
+	}).Return(nil)
+	service := s.newService(repo, true)
+	// act
+	err := service.Dispatch(context.Background())
+	// assert
+	t.Require().NoError(err)
+	repo.AssertExpectations(t)
+	<-received
+	t.Require().Equal(models.DeliveryDead, updated.Status)
+	t.Require().Equal(testMaxAttempts, updated.Attempts)
+	t.Require().Equal(http.StatusBadGateway, updated.LastStatus)
+}
+
+func (s *WebhookSuite) TestDispatchRejectsPrivateAddress(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	server, received := s.newReceiver(http.StatusNoContent)
+	defer server.Close()
+	task := s.newTask(server.URL, 0)
+	var updated models.WebhookDelivery
+	repo := new(RepositoryMock)
+	repo.On("ClaimWebhookDeliveries", mock.Anything, mock.Anything).Return([]models.WebhookDeliveryTask{task}, nil)
+	repo.On("UpdateWebhookDelivery", mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(models.WebhookDelivery)
+	}).Return(nil)
+	service := s.newService(repo, false)
+	// act
+	err := service.Dispatch(context.Background())
+	// assert
+	t.Require().NoError(err)
+	repo.AssertExpectations(t)
+	t.Require().Empty(received)
+	t.Require().Equal(models.DeliveryPending, updated.Status)
+	t.Require().Zero(updated.LastStatus)
+	t.Require().Contains(updated.LastError, models.ErrForbiddenWebhookURL.Error())
+}
+
+func (s *WebhookSuite) TestDispatchDoesNotFollowRedirects(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	target, received := s.newReceiver(http.StatusNoContent)
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+	task := s.newTask(server.URL, 0)
+	var updated models.WebhookDelivery
+	repo := new(RepositoryMock)
+	repo.On("ClaimWebhookDeliveries", mock.Anything, mock.Anything).Return([]models.WebhookDeliveryTask{task}, nil)
+	repo.On("UpdateWebhookDelivery", mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(models.WebhookDelivery)
+	}).Return(nil)
+	service := s.newService(repo, true)
+	// act
+	err := service.Dispatch(context.Background())
+	// assert
+	t.Require().NoError(err)
+	repo.AssertExpectations(t)
+	t.Require().Empty(received)
+	t.Require().Equal(models.DeliveryPending, updated.Status)
+	t.Require().Equal(http.StatusTemporaryRedirect, updated.LastStatus)
+}
+
+func (s *WebhookSuite) TestSign(t provider.T) {
+	t.Epic("Webhooks")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	body, _ := json.Marshal(map[string]string{"type": "person.updated"})
+	// act
+	signature := webhook.Sign(testSecret, "1700000000", body)
+	// assert
+	t.Require().Equal(signature, webhook.Sign(testSecret, "1700000000", body))
+	t.Require().NotEqual(signature, webhook.Sign(testSecret, "1700000001", body))
+	t.Require().NotEqual(signature, webhook.Sign("another secret", "1700000000", body))
+	t.Require().Regexp(`^sha256=[0-9a-f]{64}$`, signature)
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(WebhookSuite))
+}
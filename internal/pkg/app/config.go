@@ -1,6 +1,9 @@
 package app
 
 import (
+	"github.com/Inspirate789/ds-lab1/internal/person/outbox"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/person/webhook"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/v2"
@@ -10,8 +13,12 @@ type Config struct {
 	Logging struct {
 		Level int `koanf:"level"`
 	} `koanf:"logging"`
-	Web WebConfig `koanf:"web"`
-	DB  struct {
+	Web      WebConfig      `koanf:"web"`
+	GRPC     GRPCConfig     `koanf:"grpc"`
+	Persons  usecase.Config `koanf:"persons"`
+	Webhooks webhook.Config `koanf:"webhooks"`
+	Outbox   outbox.Config  `koanf:"outbox"`
+	DB       struct {
 		DriverName       string `koanf:"driver_name"`
 		ConnectionString string `koanf:"connection_string"`
 	} `koanf:"db"`
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/rpc"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/audit"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net"
+	"time"
+)
+
+type GRPCConfig struct {
+	Host string `koanf:"host"`
+	Port string `koanf:"port"`
+	// ActorMetadata is the request metadata key identifying who makes the changes for the audit trail.
+	ActorMetadata string `koanf:"actor_metadata"`
+}
+
+type GRPCApp struct {
+	config GRPCConfig
+	server *grpc.Server
+	logger *slog.Logger
+}
+
+const (
+	defaultActorMetadata = "x-actor"
+	// requestIDMetadata is the request and response metadata key of the request ID, as the header of the REST API.
+	requestIDMetadata = "x-request-id"
+)
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) != 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// callContext passes the actor and the request ID to the lower layers through the call context.
+// The request ID is generated if the client does not send it and is returned in the response header.
+func (g *GRPCApp) callContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := firstValue(md, requestIDMetadata)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID)) // fails only if the header is sent
+
+	return audit.WithMetadata(ctx, audit.Metadata{
+		Actor:     firstValue(md, g.config.ActorMetadata),
+		RequestID: requestID,
+	})
+}
+
+// finishCall logs the call and converts the panic of the handler into the internal error.
+func (g *GRPCApp) finishCall(ctx context.Context, method string, start time.Time, recovered any, err error) error {
+	if recovered != nil {
+		g.logger.Error(fmt.Sprintf("panic in %s: %v", method, recovered))
+		err = status.Error(codes.Internal, "internal server error")
+	}
+
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	g.logger.LogAttrs(ctx, level, "gRPC call",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("request-id", audit.FromContext(ctx).RequestID),
+	)
+
+	return err
+}
+
+func (g *GRPCApp) unaryInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp any, err error) {
+	ctx = g.callContext(ctx)
+	start := time.Now()
+
+	defer func() {
+		err = g.finishCall(ctx, info.FullMethod, start, recover(), err)
+	}()
+
+	return handler(ctx, req)
+}
+
+// contextStream replaces the context of the stream with the call context.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func (g *GRPCApp) streamInterceptor(
+	srv any,
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	ctx := g.callContext(stream.Context())
+	start := time.Now()
+
+	defer func() {
+		err = g.finishCall(ctx, info.FullMethod, start, recover(), err)
+	}()
+
+	return handler(srv, contextStream{ServerStream: stream, ctx: ctx})
+}
+
+func NewGRPCApp(config GRPCConfig, useCase delivery.UseCase, logger *slog.Logger) *GRPCApp {
+	if config.ActorMetadata == "" {
+		config.ActorMetadata = defaultActorMetadata
+	}
+
+	app := &GRPCApp{
+		config: config,
+		logger: logger,
+	}
+
+	app.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(app.unaryInterceptor),
+		grpc.ChainStreamInterceptor(app.streamInterceptor),
+	)
+	rpc.Register(app.server, useCase, logger)
+
+	return app
+}
+
+func (g *GRPCApp) Start() error {
+	listener, err := net.Listen("tcp", g.config.Host+":"+g.config.Port)
+	if err != nil {
+		return errors.Wrap(err, "start gRPC app")
+	}
+
+	return errors.Wrap(g.server.Serve(listener), "start gRPC app")
+}
+
+// Shutdown waits for the running calls to finish. The calls are canceled if the context is done earlier.
+func (g *GRPCApp) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+
+	go func() {
+		g.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		g.server.Stop()
+		return errors.Wrap(ctx.Err(), "stop gRPC app")
+	}
+}
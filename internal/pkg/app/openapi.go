@@ -0,0 +1,352 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Inspirate789/ds-lab1/api/openapi"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/errors"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type OpenAPIConfig struct {
+	// ValidateRequests rejects the requests of the operations of the specification which do not match it.
+	ValidateRequests bool `koanf:"validate_requests"`
+	// ValidateResponses is the debug mode: the responses which do not match the specification are replaced
+	// with the internal server error listing the violations.
+	ValidateResponses bool `koanf:"validate_responses"`
+}
+
+// bodyFields are the keys of the violations of the request and the response bodies themselves.
+const (
+	requestBodyField  = "body"
+	responseBodyField = "response"
+)
+
+// validateRequestBodyExtension disables the validation of the request body of the operation, whose handler
+// validates the items of the body and reports them one by one.
+const validateRequestBodyExtension = "x-validate-request-body"
+
+func init() {
+	openapi3filter.RegisterBodyDecoder("text/csv", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder(fiber.MIMETextHTML, openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("application/merge-patch+json", openapi3filter.JSONBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("application/vnd.persons.page+json", openapi3filter.JSONBodyDecoder)
+}
+
+// spec is the specification served by the app, its server is the path prefix of the web config.
+type spec struct {
+	router routers.Router
+	yaml   []byte
+}
+
+// mustLoadSpec loads the embedded specification, so it can only fail if the specification is broken.
+// The paths of the specification are relative to the server, which is replaced with the path prefix
+// to match the operations by the paths only, whatever the host of the service is.
+func mustLoadSpec(pathPrefix string) spec {
+	loader := openapi3.NewLoader()
+
+	doc, err := loader.LoadFromData(openapi.Spec)
+	if err == nil {
+		err = doc.Validate(loader.Context)
+	}
+
+	if err != nil {
+		panic("load OpenAPI specification: " + err.Error())
+	}
+
+	serverURL := pathPrefix
+	if serverURL == "" {
+		serverURL = "/"
+	}
+
+	doc.Servers = openapi3.Servers{{URL: serverURL}}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		panic("route OpenAPI specification: " + err.Error())
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		panic("marshal OpenAPI specification: " + err.Error())
+	}
+
+	return spec{router: router, yaml: data}
+}
+
+func (s spec) send(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, "application/yaml")
+	return ctx.Status(fiber.StatusOK).Send(s.yaml)
+}
+
+// mustResolveRoutes checks that every route of the API resolves to the operation of the specification,
+// otherwise the requests of the route would be passed without validation. The routes outside of the API,
+// e.g. the health checks, are excluded.
+func (s spec) mustResolveRoutes(routes, excluded []fiber.Route) {
+	var unresolved []string
+
+	for _, route := range routes {
+		if route.Method == fiber.MethodHead || slices.ContainsFunc(excluded, func(excluded fiber.Route) bool {
+			return excluded.Method == route.Method && excluded.Path == route.Path
+		}) {
+			continue
+		}
+
+		req, err := http.NewRequest(route.Method, samplePath(route), nil)
+		if err == nil {
+			_, _, err = s.router.FindRoute(req)
+		}
+
+		if err != nil {
+			unresolved = append(unresolved, route.Method+" "+route.Path)
+		}
+	}
+
+	if len(unresolved) != 0 {
+		panic("routes missing from OpenAPI specification: " + strings.Join(unresolved, ", "))
+	}
+}
+
+// samplePath returns the path matching the route, its parameters are replaced with the sample value.
+func samplePath(route fiber.Route) string {
+	path := strings.TrimSuffix(strings.ReplaceAll(route.Path, `\:`, ":"), "/")
+
+	for _, param := range route.Params {
+		path = strings.Replace(path, ":"+param, "1", 1)
+	}
+
+	return path
+}
+
+// swaggerUIPage is the Swagger UI page loaded from the CDN, it shows the specification served by the service.
+// The URL of the specification is relative, so the page works behind the proxies adding the prefix to the paths.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Persons API</title>
+	<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script crossorigin src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+	<script>
+		window.ui = SwaggerUIBundle({ url: 'openapi.yaml', dom_id: '#swagger-ui' });
+	</script>
+</body>
+</html>`
+
+func sendSwaggerUI(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.Status(fiber.StatusOK).SendString(swaggerUIPage)
+}
+
+// addViolations adds the violations of the error of the validation to the map of the field errors.
+// The errors of the parameters are keyed by their names, the errors of the bodies by the paths of their fields.
+func addViolations(violations map[string]string, field string, err error) {
+	switch err := err.(type) {
+	case openapi3.MultiError:
+		for _, err := range err {
+			addViolations(violations, field, err)
+		}
+	case *openapi3filter.RequestError:
+		if err.Parameter != nil {
+			field = err.Parameter.Name
+		}
+
+		if err.Err == nil {
+			violations[field] = err.Reason
+			return
+		}
+
+		addViolations(violations, field, err.Err)
+	case *openapi3filter.ResponseError:
+		if err.Err == nil {
+			violations[field] = err.Reason
+			return
+		}
+
+		addViolations(violations, field, err.Err)
+	case *openapi3.SchemaError:
+		path, msg := schemaViolation(err)
+		violations[schemaField(field, path)] = msg
+	default:
+		violations[field] = err.Error()
+	}
+}
+
+// schemaViolation returns the path of the violating field and the message the handlers report for it,
+// so the request gets the same errors whether the specification or the handler rejects it.
+func schemaViolation(err *openapi3.SchemaError) ([]string, string) {
+	path, schema := err.JSONPointer(), err.Schema
+
+	switch err.SchemaField {
+	case "required":
+		return path, errors.FieldRequired
+	case "properties":
+		var property string
+
+		_, scanErr := fmt.Sscanf(err.Reason, "property %q is unsupported", &property)
+		if scanErr == nil {
+			return append(path, property), errors.FieldUnknown
+		}
+	case "type":
+		// the reason is like "value must be an integer"
+		return path, strings.TrimPrefix(err.Reason, "value ")
+	case "minLength":
+		if schema.MinLength == 1 {
+			return path, errors.FieldRequired
+		}
+	case "maxLength":
+		return path, errors.FieldTooLong(int(*schema.MaxLength))
+	case "minimum", "maximum":
+		if schema.Min != nil && schema.Max != nil {
+			return path, errors.FieldOutOfRange(int(*schema.Min), int(*schema.Max))
+		}
+	}
+
+	return path, err.Reason
+}
+
+func schemaField(field string, path []string) string {
+	switch {
+	case len(path) == 0:
+		return field
+	case field == requestBodyField:
+		return strings.Join(path, ".")
+	case field == responseBodyField:
+		return field + "." + strings.Join(path, ".")
+	default:
+		return field
+	}
+}
+
+// requestViolationError returns the error of the request violating the specification. The bodies
+// of the person operations are the persons, so their violations are reported like the handlers do.
+func requestViolationError(route *routers.Route, violations map[string]string) errors.ValidationError {
+	if route.Operation.RequestBody != nil && strings.HasPrefix(route.Path, "/persons") {
+		return errors.ErrInvalidPersonFields(violations)
+	}
+
+	return errors.ErrSpecViolation(violations)
+}
+
+func validationOptions(route *routers.Route) *openapi3filter.Options {
+	validateBody, ok := route.Operation.Extensions[validateRequestBodyExtension].(bool)
+
+	return &openapi3filter.Options{
+		ExcludeRequestBody:  ok && !validateBody,
+		MultiError:          true,
+		SkipSettingDefaults: true,
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+	}
+}
+
+func responseHeader(ctx *fiber.Ctx) http.Header {
+	header := make(http.Header)
+
+	ctx.Response().Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	return header
+}
+
+// validateResponse returns the violations of the response written by the handler.
+// The streamed responses are not validated, because their bodies are written after the middlewares.
+func validateResponse(ctx context.Context, fiberCtx *fiber.Ctx, input *openapi3filter.RequestValidationInput) map[string]string {
+	if bodystream.Streamed(fiberCtx) {
+		return nil
+	}
+
+	err := openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 fiberCtx.Response().StatusCode(),
+		Header:                 responseHeader(fiberCtx),
+		Body:                   io.NopCloser(bytes.NewReader(fiberCtx.Response().Body())),
+		Options:                input.Options,
+	})
+	if err == nil {
+		return nil
+	}
+
+	violations := make(map[string]string)
+	addViolations(violations, responseBodyField, err)
+
+	return violations
+}
+
+// validateSpec validates the requests of the operations of the specification against it. Other requests, e.g. the
+// health checks, are passed as is, so are the requests with the methods the specification does not describe.
+func validateSpec(config OpenAPIConfig, spec spec, logger *slog.Logger) fiber.Handler {
+	if !config.ValidateRequests && !config.ValidateResponses {
+		return func(ctx *fiber.Ctx) error {
+			return ctx.Next()
+		}
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		req, err := adaptor.ConvertRequest(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		route, pathParams, err := spec.router.FindRoute(req)
+		if err != nil {
+			return ctx.Next()
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    validationOptions(route),
+		}
+
+		// the handlers decode the bodies without the content type as JSON
+		if req.Header.Get(fiber.HeaderContentType) == "" && len(ctx.Body()) != 0 {
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		}
+
+		if config.ValidateRequests {
+			err = openapi3filter.ValidateRequest(ctx.UserContext(), input)
+			if err != nil {
+				logger.Warn(err.Error())
+
+				violations := make(map[string]string)
+				addViolations(violations, requestBodyField, err)
+
+				return problem.Send(ctx, requestViolationError(route, violations).Problem())
+			}
+		}
+
+		err = ctx.Next()
+		if err != nil || !config.ValidateResponses {
+			return err
+		}
+
+		violations := validateResponse(ctx.UserContext(), ctx, input)
+		if violations != nil {
+			logger.Error("response does not match the API specification", slog.Any("violations", violations))
+
+			return problem.Send(ctx, problem.New(fiber.StatusInternalServerError, "response does not match the API specification").
+				With("errors", violations))
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package app_test
+
+import (
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/app"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pathPrefixes are the prefixes of the API the validation is tested with, the paths of the specification
+// are relative to the prefix.
+var pathPrefixes = []string{"/api/v1", "/people/v2"}
+
+type OpenAPISuite struct {
+	suite.Suite
+}
+
+func (s *OpenAPISuite) newApp(pathPrefix string, useCase *UseCaseMock) *app.FiberApp {
+	return s.newValidatingApp(pathPrefix, useCase, true)
+}
+
+func (*OpenAPISuite) newValidatingApp(pathPrefix string, useCase *UseCaseMock, validate bool) *app.FiberApp {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config := app.WebConfig{PathPrefix: pathPrefix, OpenAPI: app.OpenAPIConfig{ValidateRequests: validate}}
+
+	return app.NewFiberApp(config, useCase, logger)
+}
+
+func (s *OpenAPISuite) TestValidRequest(t provider.T) {
+	t.Epic("OpenAPI")
+	t.Severity(allure.CRITICAL)
+
+	for _, pathPrefix := range pathPrefixes {
+		t.Run(pathPrefix, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			useCase.On("GetPersons", mock.Anything).Return(models.PersonsPage{Persons: []models.Person{}}, nil)
+			fiberApp := s.newApp(pathPrefix, useCase)
+			req := httptest.NewRequest(http.MethodGet, pathPrefix+"/persons?limit=10", nil)
+			// act
+			res, err := fiberApp.Test(req, -1)
+			// assert
+			t.Require().NoError(err)
+			t.Require().Equal(http.StatusOK, res.StatusCode)
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func (s *OpenAPISuite) TestInvalidRequest(t provider.T) {
+	t.Epic("OpenAPI")
+	t.Severity(allure.CRITICAL)
+
+	for _, pathPrefix := range pathPrefixes {
+		t.Run(pathPrefix, func(t provider.T) {
+			// arrange
+			useCase := new(UseCaseMock)
+			fiberApp := s.newApp(pathPrefix, useCase)
+			req := httptest.NewRequest(http.MethodPost, pathPrefix+"/persons", strings.NewReader(`{"name": 1}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			// act
+			res, body := send(t, fiberApp, req)
+			// assert
+			t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+			t.Require().Equal(problem.MIMEProblemJSON, res.Header.Get(fiber.HeaderContentType))
+			t.Require().Equal("invalid person data", body["detail"])
+			t.Require().Equal(map[string]any{"name": "must be a string"}, body["errors"])
+			useCase.AssertNotCalled(t, "CreatePerson", mock.Anything)
+		})
+	}
+}
+
+func (s *OpenAPISuite) TestViolationsMatchHandlerErrors(t provider.T) {
+	t.Epic("OpenAPI")
+	t.Severity(allure.CRITICAL)
+
+	bodies := map[string]string{
+		"unknown field":      `{"name": "Aboba", "foo": 1}`,
+		"missing name":       `{"age": 20}`,
+		"empty name":         `{"name": ""}`,
+		"long name":          `{"name": "` + strings.Repeat("ж", 256) + `"}`,
+		"long address":       `{"name": "Aboba", "address": "` + strings.Repeat("a", 513) + `"}`,
+		"negative age":       `{"name": "Aboba", "age": -1}`,
+		"too large age":      `{"name": "Aboba", "age": 151}`,
+		"name of wrong type": `{"name": 1}`,
+	}
+
+	for name, requestBody := range bodies {
+		t.Run(name, func(t provider.T) {
+			// arrange
+			responses := make([]map[string]any, 0, 2)
+			// act
+			for _, validate := range []bool{true, false} {
+				fiberApp := s.newValidatingApp("/api/v1", new(UseCaseMock), validate)
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/persons", strings.NewReader(requestBody))
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				res, body := send(t, fiberApp, req)
+				t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+
+				responses = append(responses, body)
+			}
+			// assert
+			t.Require().Equal(responses[1]["detail"], responses[0]["detail"])
+			t.Require().Equal(responses[1]["errors"], responses[0]["errors"])
+		})
+	}
+}
+
+func (s *OpenAPISuite) TestRequestWithoutContentType(t provider.T) {
+	t.Epic("OpenAPI")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("CreatePerson", mock.Anything).Return(models.Person{ID: 1}, nil)
+	fiberApp := s.newApp("/api/v1", useCase)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/persons", strings.NewReader(`{"name": "Aboba"}`))
+	// act
+	res, err := fiberApp.Test(req, -1)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(http.StatusCreated, res.StatusCode)
+	useCase.AssertExpectations(t)
+}
+
+func (s *OpenAPISuite) TestServedSpecHasPathPrefix(t provider.T) {
+	t.Epic("OpenAPI")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	fiberApp := s.newApp("/people/v2", new(UseCaseMock))
+	req := httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil)
+	// act
+	res, err := fiberApp.Test(req, -1)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(http.StatusOK, res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	t.Require().NoError(err)
+	t.Require().Contains(string(body), "url: /people/v2")
+	t.Require().Contains(string(body), "/persons/{id}:")
+}
+
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(OpenAPISuite))
+}
@@ -0,0 +1,29 @@
+package app_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/stretchr/testify/mock"
+)
+
+// UseCaseMock mocks the use cases called by the tests of the app, the other use cases are not implemented.
+type UseCaseMock struct {
+	delivery.UseCase
+	mock.Mock
+}
+
+func (u *UseCaseMock) GetPersons(_ context.Context, query models.PersonsQuery) (models.PersonsPage, error) {
+	args := u.Called(query)
+	return args.Get(0).(models.PersonsPage), args.Error(1)
+}
+
+func (u *UseCaseMock) GetPerson(_ context.Context, personID int) (models.Person, error) {
+	args := u.Called(personID)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) CreatePerson(_ context.Context, person models.PersonProperties) (models.Person, error) {
+	args := u.Called(person)
+	return args.Get(0).(models.Person), args.Error(1)
+}
@@ -3,6 +3,10 @@ package app
 import (
 	"context"
 	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery/gql"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/audit"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/bodystream"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
@@ -17,21 +21,25 @@ type HealthChecker interface {
 }
 
 type WebConfig struct {
-	Host       string `koanf:"host"`
-	Port       string `koanf:"port"`
-	PathPrefix string `koanf:"path_prefix"`
+	Host       string          `koanf:"host"`
+	Port       string          `koanf:"port"`
+	PathPrefix string          `koanf:"path_prefix"`
+	Persons    delivery.Config `koanf:"persons"`
+	GraphQL    gql.Config      `koanf:"graphql"`
+	OpenAPI    OpenAPIConfig   `koanf:"openapi"`
+	// Events is the configuration of the change feed, the feed itself is created with the use cases.
+	Events delivery.EventsConfig `koanf:"events"`
+	// ActorHeader is the request header identifying who makes the changes for the audit trail.
+	ActorHeader string `koanf:"actor_header"`
 }
 
 type FiberApp struct {
 	config WebConfig
 	fiber  *fiber.App
+	events *delivery.EventFeed
 	logger *slog.Logger
 }
 
-func newFiberError(msg string) map[string]any {
-	return fiber.Map{"message": msg}
-}
-
 func checkLiveness(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).SendString("live")
 }
@@ -40,34 +48,110 @@ func checkReadiness(useCase HealthChecker) func(ctx *fiber.Ctx) error {
 	return func(ctx *fiber.Ctx) error {
 		err := useCase.HealthCheck(ctx.UserContext())
 		if err != nil {
-			return ctx.Status(fiber.StatusServiceUnavailable).JSON(newFiberError(err.Error()))
+			return errors.Wrap(fiber.NewError(fiber.StatusServiceUnavailable, "service is not ready"), err.Error())
 		}
 
 		return ctx.Status(fiber.StatusOK).SendString("ready")
 	}
 }
 
-func NewFiberApp(config WebConfig, useCase delivery.UseCase, logger *slog.Logger) *FiberApp {
+const defaultActorHeader = "X-Actor"
+
+// auditMetadata passes the actor and the request ID to the lower layers through the request context.
+func auditMetadata(actorHeader string) fiber.Handler {
+	if actorHeader == "" {
+		actorHeader = defaultActorHeader
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		ctx.SetUserContext(audit.WithMetadata(ctx.UserContext(), audit.Metadata{
+			Actor:     ctx.Get(actorHeader),
+			RequestID: slogfiber.GetRequestID(ctx),
+		}))
+
+		return ctx.Next()
+	}
+}
+
+// handleError sends the problem details of the error returned by the handler. Errors of fiber keep their status
+// and message, other errors are internal, so their messages are logged only and the request ID is sent instead.
+func handleError(ctx *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return problem.Send(ctx, problem.New(fiberErr.Code, fiberErr.Message))
+	}
+
+	return problem.Send(ctx, problem.New(fiber.StatusInternalServerError, "internal server error"))
+}
+
+// Option enables the optional feature of the web app.
+type Option func(options *options)
+
+type options struct {
+	webhooks delivery.WebhookUseCase
+	events   *delivery.EventFeed
+}
+
+// WithWebhooks adds the routes of the webhook subscriptions.
+func WithWebhooks(webhooks delivery.WebhookUseCase) Option {
+	return func(options *options) {
+		options.webhooks = webhooks
+	}
+}
+
+// WithEventFeed adds the change feed route. The feed is closed by the shutdown of the app.
+func WithEventFeed(events *delivery.EventFeed) Option {
+	return func(options *options) {
+		options.events = events
+	}
+}
+
+// NewFiberApp returns the web app of the use cases with the routes of the enabled optional features.
+func NewFiberApp(config WebConfig, useCase delivery.UseCase, logger *slog.Logger, opts ...Option) *FiberApp {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
-		ErrorHandler: func(ctx *fiber.Ctx, err error) error {
-			return ctx.Status(fiber.StatusInternalServerError).JSON(newFiberError(err.Error()))
-		},
+		ErrorHandler:          handleError,
 	})
 
-	app.Use(recover.New())
+	app.Use(bodystream.Middleware)
 	app.Use(slogfiber.New(logger))
+	app.Use(recover.New()) // after the logger, so the panics are logged as errors with the request ID
+	app.Use(auditMetadata(config.ActorHeader))
+	spec := mustLoadSpec(config.PathPrefix)
+
+	app.Use(validateSpec(config.OpenAPI, spec, logger))
 	app.Use(pprof.New())
 
 	app.Get("/health/live", checkLiveness)
 	app.Get("/health/ready", checkReadiness(useCase))
+	app.Get("/openapi.yaml", spec.send)
+	app.Get("/docs", sendSwaggerUI)
 
+	rootRoutes := app.GetRoutes(true)
 	api := app.Group(config.PathPrefix)
-	delivery.AddHandlers(api.Group("/persons"), useCase, logger)
+
+	if o.events != nil {
+		delivery.AddEventHandlers(api, o.events, logger) // before the routes of persons matching it by the ID
+	}
+
+	delivery.AddHandlers(api, config.Persons, useCase, logger)
+	gql.AddHandlers(api, config.GraphQL, useCase, logger)
+
+	if o.webhooks != nil {
+		delivery.AddWebhookHandlers(api, o.webhooks, logger)
+	}
+
+	spec.mustResolveRoutes(app.GetRoutes(true), rootRoutes)
 
 	return &FiberApp{
 		config: config,
 		fiber:  app,
+		events: o.events,
 		logger: logger,
 	}
 }
@@ -76,7 +160,12 @@ func (f *FiberApp) Start() error {
 	return errors.Wrap(f.fiber.Listen(f.config.Host+":"+f.config.Port), "start web app")
 }
 
+// Shutdown ends the streams of the change feed first, the server waits for the open responses.
 func (f *FiberApp) Shutdown(ctx context.Context) error {
+	if f.events != nil {
+		f.events.Close()
+	}
+
 	return errors.Wrap(f.fiber.ShutdownWithContext(ctx), "stop web app")
 }
 
@@ -0,0 +1,156 @@
+package app_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/app"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type WebAppSuite struct {
+	suite.Suite
+}
+
+func (*WebAppSuite) newApp(useCase *UseCaseMock) *app.FiberApp {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return app.NewFiberApp(app.WebConfig{PathPrefix: "/api/v1"}, useCase, logger)
+}
+
+// send sends the request to the app and returns the response with its decoded JSON body.
+func send(t provider.T, fiberApp *app.FiberApp, req *http.Request) (*http.Response, map[string]any) {
+	res, err := fiberApp.Test(req, -1)
+	t.Require().NoError(err)
+
+	defer res.Body.Close()
+
+	var body map[string]any
+	t.Require().NoError(json.NewDecoder(res.Body).Decode(&body))
+
+	return res, body
+}
+
+func (s *WebAppSuite) TestInvalidPersonsQuery(t provider.T) {
+	t.Epic("Web app")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	fiberApp := s.newApp(useCase)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/persons?offset=x&limit=-1&age_min=1&age_max=0", nil)
+	// act
+	res, body := send(t, fiberApp, req)
+	// assert
+	t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	t.Require().Equal(problem.MIMEProblemJSON, res.Header.Get(fiber.HeaderContentType))
+	t.Require().Equal("urn:problem-type:persons:invalid-request", body["type"])
+	t.Require().Equal("/api/v1/persons", body["instance"])
+	t.Require().Equal(map[string]any{
+		"offset":  "must be a non-negative integer",
+		"limit":   "must be a non-negative integer",
+		"age_min": "must not be greater than age_max",
+	}, body["errors"])
+	useCase.AssertNotCalled(t, "GetPersons", mock.Anything)
+}
+
+func (s *WebAppSuite) TestInvalidPerson(t provider.T) {
+	t.Epic("Web app")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	fiberApp := s.newApp(useCase)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/persons", strings.NewReader(`{"age": -1}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	// act
+	res, body := send(t, fiberApp, req)
+	// assert
+	t.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	t.Require().Equal(problem.MIMEProblemJSON, res.Header.Get(fiber.HeaderContentType))
+	t.Require().Contains(body["errors"], "name")
+	t.Require().Contains(body["errors"], "age")
+	useCase.AssertNotCalled(t, "CreatePerson", mock.Anything)
+}
+
+func (s *WebAppSuite) TestInternalErrorIsSanitized(t provider.T) {
+	t.Epic("Web app")
+	t.Severity(allure.CRITICAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", 1).Return(models.Person{}, errors.New(`pq: password authentication failed for user "persons"`))
+	fiberApp := s.newApp(useCase)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/persons/1", nil)
+	// act
+	res, body := send(t, fiberApp, req)
+	// assert
+	t.Require().Equal(http.StatusInternalServerError, res.StatusCode)
+	t.Require().Equal(problem.MIMEProblemJSON, res.Header.Get(fiber.HeaderContentType))
+	t.Require().Equal("about:blank", body["type"])
+	t.Require().Equal("internal server error", body["detail"])
+	t.Require().NotContains(body, "errors")
+	t.Require().NotEmpty(body["request_id"])
+	useCase.AssertExpectations(t)
+}
+
+func (s *WebAppSuite) TestLegacyErrorFormat(t provider.T) {
+	t.Epic("Web app")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", 1).Return(models.Person{}, usecase.NotFound(models.ErrPersonNotFound))
+	fiberApp := s.newApp(useCase)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/persons/1", nil)
+	req.Header.Set(fiber.HeaderAccept, fiber.MIMEApplicationJSON)
+	// act
+	res, body := send(t, fiberApp, req)
+	// assert
+	t.Require().Equal(http.StatusNotFound, res.StatusCode)
+	t.Require().Equal(fiber.MIMEApplicationJSON, res.Header.Get(fiber.HeaderContentType))
+	t.Require().Equal("person not found", body["message"])
+	t.Require().NotContains(body, "type")
+	useCase.AssertExpectations(t)
+}
+
+func (s *WebAppSuite) TestProblemPreferredToLegacyFormat(t provider.T) {
+	t.Epic("Web app")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", 1).Return(models.Person{}, usecase.NotFound(models.ErrPersonNotFound))
+	fiberApp := s.newApp(useCase)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/persons/1", nil)
+	req.Header.Set(fiber.HeaderAccept, "application/json;q=0.5, application/problem+json")
+	// act
+	res, body := send(t, fiberApp, req)
+	// assert
+	t.Require().Equal(http.StatusNotFound, res.StatusCode)
+	t.Require().Equal(problem.MIMEProblemJSON, res.Header.Get(fiber.HeaderContentType))
+	t.Require().Equal("urn:problem-type:persons:person-not-found", body["type"])
+	t.Require().NotContains(body, "message")
+	useCase.AssertExpectations(t)
+}
+
+func TestWebApp(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(WebAppSuite))
+}
@@ -0,0 +1,21 @@
+package audit
+
+import "context"
+
+// Metadata describes who made the change and within which request.
+type Metadata struct {
+	Actor     string
+	RequestID string
+}
+
+type metadataKey struct{}
+
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// FromContext returns the metadata stored in the context or the empty metadata if there is none.
+func FromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+	return metadata
+}
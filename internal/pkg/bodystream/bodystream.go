@@ -0,0 +1,35 @@
+package bodystream
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+type writerKey struct{}
+
+// SetWriter sets the response body stream writer when the request passes back through the Middleware.
+// Middlewares between them see the empty body, so they cannot read the whole stream into memory
+// (the request logger reads the response body to log its length).
+func SetWriter(ctx *fiber.Ctx, writer fasthttp.StreamWriter) {
+	ctx.Locals(writerKey{}, writer)
+}
+
+// Streamed reports whether the response body is set by SetWriter.
+func Streamed(ctx *fiber.Ctx) bool {
+	_, ok := ctx.Locals(writerKey{}).(fasthttp.StreamWriter)
+	return ok
+}
+
+// Middleware must be registered before the other middlewares.
+func Middleware(ctx *fiber.Ctx) error {
+	err := ctx.Next()
+	if err != nil {
+		return err
+	}
+
+	if writer, ok := ctx.Locals(writerKey{}).(fasthttp.StreamWriter); ok {
+		ctx.Context().SetBodyStreamWriter(writer)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+// Package problem implements the problem details for HTTP APIs (RFC 7807).
+package problem
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/samber/slog-fiber"
+	"maps"
+	"net/http"
+)
+
+const MIMEProblemJSON = "application/problem+json"
+
+// requestIDMember correlates the problem with the server logs.
+const requestIDMember = "request_id"
+
+// Details is the problem details object. Extensions are serialized as the members of the object.
+type Details struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// New returns the problem without the specific type, which is described by the status only.
+func New(status int, detail string) Details {
+	return Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With returns the copy of the problem with the extension member.
+func (d Details) With(key string, value any) Details {
+	extensions := make(map[string]any, len(d.Extensions)+1)
+	maps.Copy(extensions, d.Extensions)
+	extensions[key] = value
+	d.Extensions = extensions
+
+	return d
+}
+
+func (d Details) Map() map[string]any {
+	res := make(map[string]any, len(d.Extensions)+5)
+	maps.Copy(res, d.Extensions)
+
+	res["type"] = d.Type
+	res["title"] = d.Title
+	res["status"] = d.Status
+
+	if d.Detail != "" {
+		res["detail"] = d.Detail
+	}
+
+	if d.Instance != "" {
+		res["instance"] = d.Instance
+	}
+
+	return res
+}
+
+// legacyMap is the error format used before problem details: the message with the extension members.
+func (d Details) legacyMap() map[string]any {
+	res := make(map[string]any, len(d.Extensions)+1)
+	maps.Copy(res, d.Extensions)
+
+	res["message"] = d.Detail
+	if d.Detail == "" {
+		res["message"] = d.Title
+	}
+
+	return res
+}
+
+// Send writes the problem with its status. The instance is the request path and the request ID is added
+// to correlate the problem with the logs. Clients preferring application/json to application/problem+json
+// get the legacy {"message": ...} object.
+func Send(ctx *fiber.Ctx, details Details) error {
+	if details.Instance == "" {
+		details.Instance = ctx.Path()
+	}
+
+	if requestID := slogfiber.GetRequestID(ctx); requestID != "" {
+		details = details.With(requestIDMember, requestID)
+	}
+
+	ctx.Status(details.Status)
+
+	if ctx.Accepts(MIMEProblemJSON, fiber.MIMEApplicationJSON) == fiber.MIMEApplicationJSON {
+		return ctx.JSON(details.legacyMap())
+	}
+
+	return ctx.JSON(details.Map(), MIMEProblemJSON)
+}
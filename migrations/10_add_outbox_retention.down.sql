@@ -0,0 +1 @@
+drop index if exists outbox_dispatched_at_idx;
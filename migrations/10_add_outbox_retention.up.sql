@@ -0,0 +1 @@
+create index if not exists outbox_dispatched_at_idx on outbox (dispatched_at) where dispatched_at is not null;
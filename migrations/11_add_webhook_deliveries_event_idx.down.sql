@@ -0,0 +1 @@
+drop index if exists webhook_deliveries_event_idx;
@@ -0,0 +1,9 @@
+-- the deliveries enqueued twice for the same event are removed first, the delivered one is kept
+delete from webhook_deliveries where id in (
+    select id from (
+        select id, row_number() over (partition by webhook_id, event_id order by delivered_at nulls last, id) as rank
+        from webhook_deliveries
+    ) as ranked
+    where rank > 1
+);
+create unique index if not exists webhook_deliveries_event_idx on webhook_deliveries (webhook_id, event_id);
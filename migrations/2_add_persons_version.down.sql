@@ -0,0 +1 @@
+alter table persons drop column if exists version;
@@ -0,0 +1,8 @@
+create table if not exists idempotency_keys (
+    key text primary key,
+    fingerprint text not null,
+    person_id bigint not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null
+);
+create index if not exists idempotency_keys_expires_at_idx on idempotency_keys (expires_at);
@@ -0,0 +1,2 @@
+drop index if exists persons_deleted_at_idx;
+alter table persons drop column if exists deleted_at;
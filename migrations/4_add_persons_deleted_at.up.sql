@@ -0,0 +1,2 @@
+alter table persons add column if not exists deleted_at timestamptz;
+create index if not exists persons_deleted_at_idx on persons (deleted_at) where deleted_at is not null;
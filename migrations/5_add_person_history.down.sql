@@ -0,0 +1 @@
+drop table if exists person_history;
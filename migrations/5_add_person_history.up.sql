@@ -0,0 +1,12 @@
+create table if not exists person_history (
+    id bigint generated always as identity primary key,
+    person_id bigint not null,
+    version bigint not null,
+    operation text not null,
+    before jsonb,
+    after jsonb,
+    actor text not null default '',
+    request_id text not null default '',
+    changed_at timestamptz not null default now()
+);
+create index if not exists person_history_person_id_idx on person_history (person_id, id);
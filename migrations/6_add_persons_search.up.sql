@@ -0,0 +1,6 @@
+alter table persons add column if not exists search tsvector generated always as (
+    setweight(to_tsvector('simple', name), 'A') ||
+    setweight(to_tsvector('simple', address), 'B') ||
+    setweight(to_tsvector('simple', work), 'B')
+) stored;
+create index if not exists persons_search_idx on persons using gin (search);
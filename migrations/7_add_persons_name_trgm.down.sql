@@ -0,0 +1,2 @@
+drop index if exists persons_name_prefix_idx;
+drop index if exists persons_name_trgm_idx;
@@ -0,0 +1,3 @@
+create extension if not exists pg_trgm;
+create index if not exists persons_name_trgm_idx on persons using gin (name gin_trgm_ops) where deleted_at is null;
+create index if not exists persons_name_prefix_idx on persons (lower(name) text_pattern_ops) where deleted_at is null;
@@ -0,0 +1,23 @@
+create table if not exists webhooks (
+    id bigint generated always as identity primary key,
+    url text not null,
+    events text[] not null,
+    secret text not null,
+    created_at timestamptz not null default now()
+);
+create table if not exists webhook_deliveries (
+    id bigint generated always as identity primary key,
+    webhook_id bigint not null references webhooks (id) on delete cascade,
+    event_id text not null,
+    event_type text not null,
+    payload jsonb not null,
+    status text not null default 'pending',
+    attempts int not null default 0,
+    next_attempt_at timestamptz not null default now(),
+    last_error text not null default '',
+    last_status int not null default 0,
+    created_at timestamptz not null default now(),
+    delivered_at timestamptz
+);
+create index if not exists webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at, id) where status = 'pending';
+create index if not exists webhook_deliveries_webhook_id_idx on webhook_deliveries (webhook_id, id);
@@ -0,0 +1,9 @@
+create table if not exists outbox (
+    id bigint generated always as identity primary key,
+    event_id uuid not null,
+    event_type text not null,
+    person jsonb not null,
+    occurred_at timestamptz not null default now(),
+    dispatched_at timestamptz
+);
+create index if not exists outbox_undispatched_idx on outbox (id) where dispatched_at is null;
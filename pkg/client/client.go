@@ -0,0 +1,221 @@
+// Package client is the Go client of the persons REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPathPrefix     = "/api/v1"
+	defaultTimeout        = 10 * time.Second
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 2 * time.Second
+
+	headerIdempotencyKey = "Idempotency-Key"
+	mimeProblemJSON      = "application/problem+json"
+	mimeMergePatchJSON   = "application/merge-patch+json"
+)
+
+type RetryConfig struct {
+	// MaxAttempts is the number of attempts of the request including the first one, 1 disables the retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, it is doubled for every next retry up to MaxBackoff.
+	// The Retry-After header of the response overrides the delay.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+type Config struct {
+	// BaseURL is the scheme and the host of the service, e.g. "http://localhost:8080".
+	BaseURL string
+	// PathPrefix is the path prefix of the persons API, "/api/v1" by default.
+	PathPrefix string
+	// Timeout limits every attempt of the request.
+	Timeout time.Duration
+	Retry   RetryConfig
+	// HTTPClient sends the requests, the client with Timeout is used by default.
+	HTTPClient *http.Client
+}
+
+type Client struct {
+	baseURL    string
+	pathPrefix string
+	retry      RetryConfig
+	httpClient *http.Client
+}
+
+func New(config Config) *Client {
+	if config.PathPrefix == "" {
+		config.PathPrefix = defaultPathPrefix
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	if config.Retry.MaxAttempts <= 0 {
+		config.Retry.MaxAttempts = defaultMaxAttempts
+	}
+
+	if config.Retry.InitialBackoff <= 0 {
+		config.Retry.InitialBackoff = defaultInitialBackoff
+	}
+
+	if config.Retry.MaxBackoff <= 0 {
+		config.Retry.MaxBackoff = defaultMaxBackoff
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: config.Timeout}
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(config.BaseURL, "/"),
+		pathPrefix: "/" + strings.Trim(config.PathPrefix, "/"),
+		retry:      config.Retry,
+		httpClient: config.HTTPClient,
+	}
+}
+
+// request is the request of the client. The body is kept to send it again on retries.
+type request struct {
+	method      string
+	path        string
+	query       string
+	contentType string
+	body        []byte
+	header      http.Header
+}
+
+func jsonRequest(method, path string, body any) (request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return request{}, err
+	}
+
+	return request{method: method, path: path, contentType: "application/json", body: data}, nil
+}
+
+// retryable reports whether the request failed with the server error, which may be gone on the next attempt.
+func retryable(resp *http.Response) bool {
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the delay before the retry: the Retry-After delay or the exponential delay with the jitter.
+func (c *Client) backoff(retry int, resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, c.retry.MaxBackoff)
+	}
+
+	delay := min(c.retry.InitialBackoff<<retry, c.retry.MaxBackoff)
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (c *Client) newHTTPRequest(ctx context.Context, req request) (*http.Request, error) {
+	url := c.baseURL + req.path
+	if req.query != "" {
+		url += "?" + req.query
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, url, bytes.NewReader(req.body))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+
+	httpReq.Header.Set("Accept", "application/json, "+mimeProblemJSON)
+
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+
+	return httpReq, nil
+}
+
+// do sends the request with the retries and returns the successful response. The responses with the error
+// statuses are returned as errors, the body of the returned response must be closed.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		httpReq, err := c.newHTTPRequest(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		if !retryable(resp) || attempt == c.retry.MaxAttempts {
+			return nil, readError(resp)
+		}
+
+		delay := c.backoff(attempt-1, resp)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// doJSON sends the request and decodes the JSON response into the result.
+func (c *Client) doJSON(ctx context.Context, req request, result any) (http.Header, error) {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Header, nil
+}
+
+// doEmpty sends the request whose response body is not needed.
+func (c *Client) doEmpty(ctx context.Context, req request) (http.Header, error) {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.Header, nil
+}
+
+// newIdempotencyKey returns the key which makes the retries of the creation safe.
+func newIdempotencyKey() http.Header {
+	return http.Header{headerIdempotencyKey: {uuid.NewString()}}
+}
+
+// Health checks whether the service is ready to serve the requests.
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.doEmpty(ctx, request{method: http.MethodGet, path: "/health/ready"})
+	return err
+}
@@ -0,0 +1,268 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/usecase"
+	"github.com/Inspirate789/ds-lab1/internal/pkg/app"
+	"github.com/Inspirate789/ds-lab1/pkg/client"
+	"github.com/ozontech/allure-go/pkg/allure"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type ClientSuite struct {
+	suite.Suite
+}
+
+// roundTripper sends the requests of the client to the app without the network.
+type roundTripper struct {
+	app *app.FiberApp
+}
+
+// age returns the pointer to the age of the person.
+func age(value int) *int {
+	return &value
+}
+
+func (r roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.app.Test(req, -1)
+}
+
+func (*ClientSuite) newClient(useCase *UseCaseMock) *client.Client {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	fiberApp := app.NewFiberApp(app.WebConfig{PathPrefix: "/api/v1"}, useCase, logger)
+
+	return client.New(client.Config{
+		BaseURL: "http://persons.test",
+		Retry: client.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		},
+		HTTPClient: &http.Client{Transport: roundTripper{app: fiberApp}},
+	})
+}
+
+func (*ClientSuite) newPerson(id int) models.Person {
+	return models.Person{
+		ID: id,
+		PersonProperties: models.PersonProperties{
+			Name:    "Aboba",
+			Age:     age(id),
+			Address: "Address",
+			Work:    "Work",
+		},
+	}
+}
+
+func (s *ClientSuite) TestList(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	firstPage := mock.MatchedBy(func(query models.PersonsQuery) bool {
+		return query.Keyset && query.Limit == 2 && query.Cursor == nil && query.Filter.Work == "Work"
+	})
+	nextPage := mock.MatchedBy(func(query models.PersonsQuery) bool {
+		return query.Keyset && query.Cursor != nil && query.Cursor.Boundary.ID == 2
+	})
+	useCase.On("GetPersons", firstPage).Return(models.PersonsPage{
+		Persons:    []models.Person{s.newPerson(1), s.newPerson(2)},
+		HasMore:    true,
+		NextCursor: &models.Cursor{Boundary: s.newPerson(2)},
+	}, nil)
+	useCase.On("GetPersons", nextPage).Return(models.PersonsPage{
+		Persons: []models.Person{s.newPerson(3)},
+	}, nil)
+	c := s.newClient(useCase)
+	// act
+	var ids []int
+	for person, err := range c.List(context.Background(), client.ListOptions{Work: "Work", PageSize: 2}) {
+		t.Require().NoError(err)
+		ids = append(ids, person.ID)
+	}
+	// assert
+	t.Require().Equal([]int{1, 2, 3}, ids)
+	useCase.AssertExpectations(t)
+	useCase.AssertNumberOfCalls(t, "GetPersons", 2)
+}
+
+func (s *ClientSuite) TestGet(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	person := s.newPerson(1)
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", person.ID).Return(person, nil)
+	c := s.newClient(useCase)
+	// act
+	res, err := c.Get(context.Background(), person.ID)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(person.ID, res.ID)
+	t.Require().Equal(person.Name, res.Name)
+	t.Require().Equal(person.Age, res.Age)
+	useCase.AssertExpectations(t)
+}
+
+func (s *ClientSuite) TestGetNotFound(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", 1).Return(models.Person{}, usecase.NotFound(models.ErrPersonNotFound))
+	c := s.newClient(useCase)
+	// act
+	_, err := c.Get(context.Background(), 1)
+	// assert
+	var notFoundErr *client.NotFoundError
+	t.Require().ErrorAs(err, &notFoundErr)
+	t.Require().Equal(http.StatusNotFound, notFoundErr.Status)
+	useCase.AssertNumberOfCalls(t, "GetPerson", 1)
+}
+
+func (s *ClientSuite) TestCreate(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	person := s.newPerson(7)
+	useCase := new(UseCaseMock)
+	useCase.On("CreatePersonOnce", mock.AnythingOfType("string"), person.PersonProperties).
+		Return(models.IdempotentResult{PersonID: person.ID}, nil)
+	c := s.newClient(useCase)
+	// act
+	id, err := c.Create(context.Background(), client.PersonProperties{
+		Name:    person.Name,
+		Age:     person.Age,
+		Address: person.Address,
+		Work:    person.Work,
+	})
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(person.ID, id)
+	useCase.AssertExpectations(t)
+}
+
+func (s *ClientSuite) TestCreateInvalid(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	c := s.newClient(useCase)
+	// act
+	_, err := c.Create(context.Background(), client.PersonProperties{Age: age(200)})
+	// assert
+	var validationErr *client.ValidationError
+	t.Require().ErrorAs(err, &validationErr)
+	t.Require().Contains(validationErr.Errors, "name")
+	t.Require().Contains(validationErr.Errors, "age")
+	useCase.AssertNotCalled(t, "CreatePersonOnce", mock.Anything, mock.Anything)
+}
+
+func (s *ClientSuite) TestPatch(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	person := s.newPerson(1)
+	work := "New work"
+	useCase := new(UseCaseMock)
+	useCase.On("PatchPerson", person.ID, models.AnyVersion).Return(person, nil)
+	c := s.newClient(useCase)
+	// act
+	res, err := c.Patch(context.Background(), person.ID, client.PersonPatch{Work: &work})
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(work, res.Work)
+	t.Require().Equal(person.Name, res.Name)
+	useCase.AssertExpectations(t)
+}
+
+func (s *ClientSuite) TestDelete(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("DeletePerson", 1, models.AnyVersion).Return(nil)
+	c := s.newClient(useCase)
+	// act
+	err := c.Delete(context.Background(), 1)
+	// assert
+	t.Require().NoError(err)
+	useCase.AssertExpectations(t)
+}
+
+func (s *ClientSuite) TestRetry(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	person := s.newPerson(1)
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", person.ID).Return(models.Person{}, usecase.Unavailable(errors.New("connection refused"))).Once()
+	useCase.On("GetPerson", person.ID).Return(person, nil).Once()
+	c := s.newClient(useCase)
+	// act
+	res, err := c.Get(context.Background(), person.ID)
+	// assert
+	t.Require().NoError(err)
+	t.Require().Equal(person.ID, res.ID)
+	useCase.AssertExpectations(t)
+	useCase.AssertNumberOfCalls(t, "GetPerson", 2)
+}
+
+func (s *ClientSuite) TestRetryExhausted(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("GetPerson", 1).Return(models.Person{}, usecase.Unavailable(errors.New("connection refused")))
+	c := s.newClient(useCase)
+	// act
+	_, err := c.Get(context.Background(), 1)
+	// assert
+	var responseErr *client.ResponseError
+	t.Require().ErrorAs(err, &responseErr)
+	t.Require().Equal(http.StatusServiceUnavailable, responseErr.Status)
+	useCase.AssertNumberOfCalls(t, "GetPerson", 3)
+}
+
+func (s *ClientSuite) TestHealth(t provider.T) {
+	t.Epic("Client")
+	t.Severity(allure.NORMAL)
+
+	// arrange
+	useCase := new(UseCaseMock)
+	useCase.On("HealthCheck").Return(nil)
+	c := s.newClient(useCase)
+	// act
+	err := c.Health(context.Background())
+	// assert
+	t.Require().NoError(err)
+	useCase.AssertExpectations(t)
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	suite.RunSuite(t, new(ClientSuite))
+}
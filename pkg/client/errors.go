@@ -0,0 +1,94 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxErrorBodySize limits the body of the error response read by the client.
+const maxErrorBodySize = 1 << 20
+
+// Problem is the problem details (RFC 7807) sent by the service with the error statuses.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	RequestID string `json:"request_id"`
+}
+
+func (p Problem) message() string {
+	message := "persons API: " + strconv.Itoa(p.Status)
+
+	if p.Detail != "" {
+		return message + " " + p.Detail
+	}
+
+	if p.Title != "" {
+		return message + " " + p.Title
+	}
+
+	return message + " " + http.StatusText(p.Status)
+}
+
+// NotFoundError is returned if the person does not exist.
+type NotFoundError struct {
+	Problem
+}
+
+func (e *NotFoundError) Error() string {
+	return e.message()
+}
+
+// ValidationError is returned if the service rejects the request. Errors are the messages keyed by the fields.
+type ValidationError struct {
+	Problem
+	Errors map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	return e.message()
+}
+
+// ResponseError is returned for the other error statuses.
+type ResponseError struct {
+	Problem
+}
+
+func (e *ResponseError) Error() string {
+	return e.message()
+}
+
+// readError reads the problem from the error response and closes its body.
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	var body struct {
+		Problem
+		Message string            `json:"message"`
+		Errors  map[string]string `json:"errors"`
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err == nil {
+		_ = json.Unmarshal(data, &body)
+	}
+
+	body.Status = resp.StatusCode
+
+	if body.Detail == "" {
+		body.Detail = body.Message
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return &NotFoundError{Problem: body.Problem}
+	case http.StatusBadRequest:
+		return &ValidationError{Problem: body.Problem, Errors: body.Errors}
+	default:
+		return &ResponseError{Problem: body.Problem}
+	}
+}
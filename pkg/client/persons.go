@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPageSize = 100
+
+// ErrInvalidLocation is returned if the ID of the created person cannot be parsed from the Location header.
+var ErrInvalidLocation = errors.New("persons API: invalid location of the created person")
+
+type PersonProperties struct {
+	Name string `json:"name"`
+	// Age is nil if it is unknown.
+	Age     *int   `json:"age,omitempty"`
+	Address string `json:"address,omitempty"`
+	Work    string `json:"work,omitempty"`
+}
+
+type Person struct {
+	ID int `json:"id"`
+	PersonProperties
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// PersonPatch is the JSON Merge Patch (RFC 7386) of the person, the nil fields are not changed.
+type PersonPatch struct {
+	Name    *string `json:"name,omitempty"`
+	Age     *int    `json:"age,omitempty"`
+	Address *string `json:"address,omitempty"`
+	Work    *string `json:"work,omitempty"`
+}
+
+// DeletedFilter selects the persons by their presence in the trash, the deleted persons are excluded by default.
+type DeletedFilter string
+
+const (
+	DeletedExclude DeletedFilter = ""
+	DeletedOnly    DeletedFilter = "only"
+	DeletedInclude DeletedFilter = "include"
+)
+
+type ListOptions struct {
+	NameContains    string
+	NamePrefix      string
+	Work            string
+	AgeMin          *int
+	AgeMax          *int
+	AddressContains string
+	Deleted         DeletedFilter
+	// Sort is the list of the fields, a leading minus means descending order, e.g. "-age".
+	Sort []string
+	// PageSize is the number of persons requested at once, 100 by default.
+	PageSize int
+}
+
+func (o ListOptions) query(cursor string) url.Values {
+	values := url.Values{}
+
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	set("name", o.NameContains)
+	set("name_prefix", o.NamePrefix)
+	set("work", o.Work)
+	set("address", o.AddressContains)
+	set("deleted", string(o.Deleted))
+	set("sort", strings.Join(o.Sort, ","))
+
+	if o.AgeMin != nil {
+		values.Set("age_min", strconv.Itoa(*o.AgeMin))
+	}
+
+	if o.AgeMax != nil {
+		values.Set("age_max", strconv.Itoa(*o.AgeMax))
+	}
+
+	pageSize := o.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	values.Set("limit", strconv.Itoa(pageSize))
+	values.Set("cursor", cursor)
+
+	return values
+}
+
+type personsPage struct {
+	Persons    []Person `json:"persons"`
+	NextCursor string   `json:"next_cursor"`
+}
+
+func (c *Client) personsPath() string {
+	return c.pathPrefix + "/persons"
+}
+
+func (c *Client) personPath(id int) string {
+	return c.personsPath() + "/" + strconv.Itoa(id)
+}
+
+// List iterates over the persons matching the options. The pages are requested with the cursors while the iteration
+// goes on, so the persons added or removed meanwhile are neither skipped nor repeated. The iteration stops
+// after the first error.
+func (c *Client) List(ctx context.Context, options ListOptions) iter.Seq2[Person, error] {
+	return func(yield func(Person, error) bool) {
+		cursor := ""
+
+		for {
+			var page personsPage
+
+			_, err := c.doJSON(ctx, request{
+				method: http.MethodGet,
+				path:   c.personsPath(),
+				query:  options.query(cursor).Encode(),
+			}, &page)
+			if err != nil {
+				yield(Person{}, err)
+				return
+			}
+
+			for _, person := range page.Persons {
+				if !yield(person, nil) {
+					return
+				}
+			}
+
+			if page.NextCursor == "" {
+				return
+			}
+
+			cursor = page.NextCursor
+		}
+	}
+}
+
+func (c *Client) Get(ctx context.Context, id int) (Person, error) {
+	var person Person
+
+	_, err := c.doJSON(ctx, request{method: http.MethodGet, path: c.personPath(id)}, &person)
+
+	return person, err
+}
+
+// Create creates the person and returns its ID. The request is sent with the idempotency key,
+// so the retries do not create the person twice.
+func (c *Client) Create(ctx context.Context, properties PersonProperties) (int, error) {
+	req, err := jsonRequest(http.MethodPost, c.personsPath(), properties)
+	if err != nil {
+		return 0, err
+	}
+
+	req.header = newIdempotencyKey()
+
+	header, err := c.doEmpty(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	location, err := url.Parse(header.Get("Location"))
+	if err != nil {
+		return 0, ErrInvalidLocation
+	}
+
+	id, err := strconv.Atoi(path.Base(location.Path))
+	if err != nil {
+		return 0, ErrInvalidLocation
+	}
+
+	return id, nil
+}
+
+// Patch updates the set fields of the person and returns the updated person.
+func (c *Client) Patch(ctx context.Context, id int, patch PersonPatch) (Person, error) {
+	req, err := jsonRequest(http.MethodPatch, c.personPath(id), patch)
+	if err != nil {
+		return Person{}, err
+	}
+
+	req.contentType = mimeMergePatchJSON
+
+	var person Person
+
+	_, err = c.doJSON(ctx, req, &person)
+
+	return person, err
+}
+
+// Delete moves the person to the trash.
+func (c *Client) Delete(ctx context.Context, id int) error {
+	_, err := c.doEmpty(ctx, request{method: http.MethodDelete, path: c.personPath(id)})
+	return err
+}
@@ -0,0 +1,58 @@
+package client_test
+
+import (
+	"context"
+	"github.com/Inspirate789/ds-lab1/internal/models"
+	"github.com/Inspirate789/ds-lab1/internal/person/delivery"
+	"github.com/stretchr/testify/mock"
+)
+
+// UseCaseMock mocks the use cases called by the client, the other use cases are not implemented.
+// PatchPerson applies the patch to the person passed to Return.
+type UseCaseMock struct {
+	delivery.UseCase
+	mock.Mock
+}
+
+func (u *UseCaseMock) HealthCheck(_ context.Context) error {
+	args := u.Called()
+	return args.Error(0)
+}
+
+func (u *UseCaseMock) GetPersons(_ context.Context, query models.PersonsQuery) (models.PersonsPage, error) {
+	args := u.Called(query)
+	return args.Get(0).(models.PersonsPage), args.Error(1)
+}
+
+func (u *UseCaseMock) GetPerson(_ context.Context, personID int) (models.Person, error) {
+	args := u.Called(personID)
+	return args.Get(0).(models.Person), args.Error(1)
+}
+
+func (u *UseCaseMock) CreatePersonOnce(
+	_ context.Context,
+	idempotencyKey string,
+	person models.PersonProperties,
+) (models.IdempotentResult, error) {
+	args := u.Called(idempotencyKey, person)
+	return args.Get(0).(models.IdempotentResult), args.Error(1)
+}
+
+func (u *UseCaseMock) PatchPerson(
+	_ context.Context,
+	personID int,
+	patch models.PersonPatch,
+	expectedVersion int64,
+) (models.Person, error) {
+	args := u.Called(personID, expectedVersion)
+	if err := args.Error(1); err != nil {
+		return models.Person{}, err
+	}
+
+	return patch.Apply(args.Get(0).(models.Person))
+}
+
+func (u *UseCaseMock) DeletePerson(_ context.Context, personID int, expectedVersion int64) error {
+	args := u.Called(personID, expectedVersion)
+	return args.Error(0)
+}